@@ -2,19 +2,27 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/spf13/cobra"
 	"github.com/superkoh/worktree-manager/internal/config"
 	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/hooks"
+	"github.com/superkoh/worktree-manager/internal/resources"
 	"github.com/superkoh/worktree-manager/internal/setup"
+	"github.com/superkoh/worktree-manager/internal/shell"
 	"github.com/superkoh/worktree-manager/internal/tui"
 )
 
 var (
 	addNewBranch bool
 	addNoSetup   bool
+	addNoHooks   bool
 	addPrintPath bool
+	addRemote    string
+	addFetch     bool
 )
 
 var addCmd = &cobra.Command{
@@ -23,31 +31,54 @@ var addCmd = &cobra.Command{
 	Long: `Create a new worktree for the specified branch.
 
 If no branch is specified, an interactive selector will be shown.
-Use -b to create a new branch.`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runAdd,
+Use -b to create a new branch.
+
+If the branch only exists on a remote, a local branch tracking it is
+created. The branch may be qualified with the remote (upstream/feature);
+use --remote to choose when several remotes have the same branch.
+
+Ports declared under resources.ports in .wt.json are allocated to the
+new worktree and exported to hooks as WT_PORT_<NAME>.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeBranches,
+	RunE:              runAdd,
 }
 
 func init() {
 	addCmd.Flags().BoolVarP(&addNewBranch, "new-branch", "b", false, "Create a new branch")
 	addCmd.Flags().BoolVar(&addNoSetup, "no-setup", false, "Skip copy/link setup")
+	addCmd.Flags().BoolVar(&addNoHooks, "no-hooks", false, "Skip lifecycle hooks")
+	addCmd.Flags().StringVar(&addRemote, "remote", "", "Remote to track the branch from")
+	addCmd.Flags().BoolVar(&addFetch, "fetch", false, "Fetch from the remote before resolving the branch")
 	addCmd.Flags().BoolVar(&addPrintPath, "print-path", false, "Print worktree path (for shell integration)")
+	addCmd.RegisterFlagCompletionFunc("remote", completeRemotes)
 	rootCmd.AddCommand(addCmd)
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
 	// Detect repository
-	repo, err := git.DetectRepository()
+	repo, err := git.DetectRepository(cmd.Context())
 	if err != nil {
 		return err
 	}
 
 	// Load configuration
-	cfg, err := config.Load(repo.RootPath)
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if addFetch {
+		// Keep stdout clean for shell integration
+		out := os.Stdout
+		if addPrintPath {
+			out = os.Stderr
+		}
+		if err := repo.FetchRemote(addRemote, out); err != nil {
+			return err
+		}
+	}
+
 	var branch string
 
 	// Get branch from args or TUI
@@ -65,7 +96,9 @@ func runAdd(cmd *cobra.Command, args []string) error {
 
 		// Create items for TUI
 		var items []tui.Item
+		isLocal := make(map[string]bool, len(branches))
 		for _, b := range branches {
+			isLocal[b] = true
 			items = append(items, tui.Item{
 				Name:        b,
 				Description: "local",
@@ -73,19 +106,13 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		}
 		for _, b := range remoteBranches {
 			// Skip if already in local branches
-			isLocal := false
-			for _, lb := range branches {
-				if lb == b {
-					isLocal = true
-					break
-				}
-			}
-			if !isLocal {
-				items = append(items, tui.Item{
-					Name:        b,
-					Description: "remote",
-				})
+			if isLocal[b.Name] || (addRemote != "" && b.Remote != addRemote) {
+				continue
 			}
+			items = append(items, tui.Item{
+				Name:        b.Ref(),
+				Description: "remote",
+			})
 		}
 
 		selected, err := tui.SelectBranch(items)
@@ -98,8 +125,22 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		branch = selected.Name
 	}
 
+	// Resolve branches that only exist on a remote
+	var remoteBranch *git.RemoteBranch
+	if !addNewBranch && !repo.BranchExists(branch) {
+		remoteBranch, err = repo.ResolveRemoteBranch(branch, addRemote)
+		if err != nil {
+			return err
+		}
+		branch = remoteBranch.Name
+		if repo.BranchExists(branch) {
+			// A qualified name such as origin/main whose local branch exists
+			remoteBranch = nil
+		}
+	}
+
 	// Generate worktree path
-	basedir, err := cfg.GetWorktreeBasedir(repo.RootPath)
+	basedir, err := cfg.GetWorktreeBasedir(repo.MainPath)
 	if err != nil {
 		return fmt.Errorf("failed to get basedir: %w", err)
 	}
@@ -107,20 +148,46 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	worktreeName := cfg.GenerateWorktreeName(repo.Name, branch)
 	worktreePath := filepath.Join(basedir, worktreeName)
 
+	// Reserve ports before creating the worktree
+	ports, err := allocateResources(repo, cfg, worktreePath)
+	if err != nil {
+		return fmt.Errorf("failed to allocate resources: %w", err)
+	}
+
 	// Create worktree
 	manager := git.NewManager(repo)
 
 	if !addPrintPath {
 		fmt.Printf("Creating worktree at: %s\n", worktreePath)
+		printPorts(ports)
 	}
 
-	if err := manager.Add(worktreePath, branch, addNewBranch, addPrintPath); err != nil {
+	if remoteBranch != nil {
+		if !addPrintPath {
+			fmt.Printf("Tracking remote branch: %s\n", remoteBranch.Ref())
+		}
+		err = manager.AddTracking(worktreePath, *remoteBranch, addPrintPath)
+	} else {
+		err = manager.Add(worktreePath, branch, addNewBranch, addPrintPath)
+	}
+	if err != nil {
+		// Free the ports unless they belong to an existing worktree at the path
+		if _, findErr := manager.FindByPath(worktreePath); findErr != nil {
+			updateResources(repo, func(r *resources.Registry) { r.Release(worktreePath) })
+		}
 		return err
 	}
 
-	// Run setup (copy/link)
+	hookCtx := newHookContext(repo, manager, worktreePath, branch)
+
+	// Run setup (copy/template/link)
 	if !addNoSetup {
-		if err := setup.RunSetup(cfg, repo.RootPath, worktreePath, addPrintPath); err != nil {
+		source, err := setupSource(repo, manager, cfg)
+		if err == nil {
+			data := newTemplateData(repo, hookCtx)
+			err = setup.RunSetup(cmd.Context(), cfg, source, worktreePath, data, addPrintPath)
+		}
+		if err != nil {
 			// Don't fail, just warn
 			if !addPrintPath {
 				fmt.Printf("Warning: setup failed: %v\n", err)
@@ -128,13 +195,39 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Print path for shell integration
+	// Run post-create hooks
+	if !addNoHooks {
+		if err := hooks.Run(cfg, hooks.PostCreate, hookCtx, addPrintPath); err != nil {
+			return err
+		}
+	}
+
+	// Hand the path to the shell integration
+	changed, err := shell.RequestCd(worktreePath)
+	if err != nil {
+		return err
+	}
+
 	if addPrintPath {
 		fmt.Println(worktreePath)
 	} else {
 		fmt.Printf("\nWorktree created successfully!\n")
-		fmt.Printf("  cd %s\n", worktreePath)
+		if !changed {
+			fmt.Printf("  cd %s\n", worktreePath)
+		}
 	}
 
 	return nil
 }
+
+// printPorts prints the ports allocated to a worktree
+func printPorts(ports map[string]int) {
+	names := make([]string, 0, len(ports))
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("Allocated port %s: %d\n", name, ports[name])
+	}
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/hooks"
+	"github.com/superkoh/worktree-manager/internal/resources"
+)
+
+var (
+	cleanBase         string
+	cleanStaleDays    int
+	cleanDeleteBranch bool
+	cleanForce        int
+	cleanDryRun       bool
+	cleanJSON         bool
+	cleanYes          bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove merged and stale worktrees",
+	Long: `Find worktrees that are no longer needed and remove them.
+
+A worktree is a candidate when its branch is merged into the base branch,
+its upstream branch is gone, or (with --stale) it has had no commits for
+the given number of days. The main and current worktrees are never removed.
+A branch with no commits of its own, such as one just created, does not
+count as merged.
+
+Dirty worktrees are skipped unless -f is given, locked ones unless -f -f is given.
+Use -D to also delete the branches and --dry-run to only show the plan.`,
+	Args: cobra.NoArgs,
+	RunE: runClean,
+}
+
+func init() {
+	cleanCmd.Flags().StringVar(&cleanBase, "base", "", "Base branch for merge detection (default: clean.base or the default branch)")
+	cleanCmd.Flags().IntVar(&cleanStaleDays, "stale", -1, "Also remove worktrees without commits for N days (default: clean.stale-days)")
+	cleanCmd.Flags().BoolVarP(&cleanDeleteBranch, "delete-branch", "D", false, "Also delete the branches")
+	cleanCmd.Flags().CountVarP(&cleanForce, "force", "f", "Remove dirty worktrees too (twice to include locked ones)")
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Show the plan without removing anything")
+	cleanCmd.Flags().BoolVar(&cleanJSON, "json", false, "Output the plan in JSON format (implies --dry-run)")
+	cleanCmd.Flags().BoolVarP(&cleanYes, "yes", "y", false, "Do not ask for confirmation")
+	cleanCmd.RegisterFlagCompletionFunc("base", completeLocalBranches)
+	rootCmd.AddCommand(cleanCmd)
+}
+
+// cleanCandidate is a worktree considered for removal
+type cleanCandidate struct {
+	Path    string
+	Branch  string
+	Reasons []string
+	Skip    string `json:",omitempty"`
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	base := cleanBase
+	if base == "" {
+		base = cfg.Clean.Base
+	}
+	if base == "" {
+		if base, err = repo.DefaultBranch(); err != nil {
+			return fmt.Errorf("failed to determine base branch, use --base: %w", err)
+		}
+	}
+
+	staleDays := cleanStaleDays
+	if staleDays < 0 {
+		staleDays = cfg.Clean.StaleDays
+	}
+
+	manager := git.NewManager(repo)
+	plan, err := buildCleanPlan(repo, manager, base, staleDays)
+	if err != nil {
+		return err
+	}
+
+	if cleanJSON {
+		if plan == nil {
+			plan = []cleanCandidate{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("Nothing to clean.")
+		return nil
+	}
+
+	printCleanPlan(plan)
+
+	var toRemove []cleanCandidate
+	for _, c := range plan {
+		if c.Skip == "" {
+			toRemove = append(toRemove, c)
+		}
+	}
+
+	if cleanDryRun || len(toRemove) == 0 {
+		return nil
+	}
+
+	if !cleanYes && !confirm(fmt.Sprintf("\nRemove %d worktree(s)?", len(toRemove))) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	removed := 0
+	for _, c := range toRemove {
+		hookCtx := newHookContext(repo, manager, c.Path, c.Branch)
+		if err := hooks.Run(cfg, hooks.PreRemove, hookCtx, false); err != nil {
+			fmt.Printf("Warning: skipping %s: %v\n", c.Path, err)
+			continue
+		}
+
+		fmt.Printf("Removing worktree: %s (%s)\n", c.Path, c.Branch)
+		if err := manager.Remove(c.Path, cleanForce); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		updateResources(repo, func(r *resources.Registry) { r.Release(c.Path) })
+		removed++
+
+		if cleanDeleteBranch {
+			fmt.Printf("Deleting branch: %s\n", c.Branch)
+			if err := manager.DeleteBranch(c.Branch, cleanForce > 0); err != nil {
+				fmt.Printf("Warning: failed to delete branch %s: %v\n", c.Branch, err)
+			}
+		}
+
+		if err := hooks.Run(cfg, hooks.PostRemove, hookCtx, false); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	fmt.Printf("\nRemoved %d worktree(s).\n", removed)
+	return nil
+}
+
+// buildCleanPlan collects worktrees matching the clean criteria
+func buildCleanPlan(repo *git.Repository, manager *git.Manager, base string, staleDays int) ([]cleanCandidate, error) {
+	worktrees, err := manager.List()
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := repo.ListMergedBranches(base)
+	if err != nil {
+		return nil, err
+	}
+	gone, err := repo.ListGoneBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	isMerged := toSet(merged)
+	isGone := toSet(gone)
+
+	// The first entry is the main worktree and is never cleaned
+	if len(worktrees) > 0 {
+		worktrees = worktrees[1:]
+	}
+	manager.LoadStatus(worktrees)
+
+	var plan []cleanCandidate
+	for _, wt := range worktrees {
+		if wt.IsBare || wt.IsPrunable || wt.Branch == "" || wt.Branch == "(detached)" || wt.Branch == base {
+			continue
+		}
+
+		var reasons []string
+		if isMerged[wt.Branch] {
+			reasons = append(reasons, "merged into "+base)
+		}
+		if isGone[wt.Branch] {
+			reasons = append(reasons, "upstream gone")
+		}
+		if staleDays > 0 && wt.Status != nil && !wt.Status.LastCommitTime.IsZero() {
+			age := time.Since(wt.Status.LastCommitTime)
+			if age > time.Duration(staleDays)*24*time.Hour {
+				reasons = append(reasons, fmt.Sprintf("no commits for %d days", int(age.Hours()/24)))
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		c := cleanCandidate{Path: wt.Path, Branch: wt.Branch, Reasons: reasons}
+		switch {
+		case wt.IsCurrent:
+			c.Skip = "current worktree"
+		case wt.IsLocked && cleanForce < 2:
+			c.Skip = "locked"
+			if wt.LockReason != "" {
+				c.Skip += ": " + wt.LockReason
+			}
+		case wt.Status != nil && wt.Status.IsDirty() && cleanForce < 1:
+			c.Skip = "uncommitted changes"
+		}
+		plan = append(plan, c)
+	}
+
+	return plan, nil
+}
+
+// printCleanPlan prints the clean plan as a table
+func printCleanPlan(plan []cleanCandidate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tPATH\tREASON\tACTION")
+	fmt.Fprintln(w, "------\t----\t------\t------")
+
+	for _, c := range plan {
+		action := "remove"
+		if c.Skip != "" {
+			action = "skip (" + c.Skip + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Branch, c.Path, strings.Join(c.Reasons, ", "), action)
+	}
+
+	w.Flush()
+}
+
+// confirm asks a yes/no question on stdin
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
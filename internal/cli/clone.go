@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/shell"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <url> [dir]",
+	Short: "Clone a repository as a bare hub for worktrees",
+	Long: `Clone a repository into a hub directory laid out for worktrees:
+
+  <dir>/.bare       the bare repository
+  <dir>/.git        a file pointing git at .bare
+  <dir>/.wt.json    creating worktrees inside the hub, named after branches
+  <dir>/<branch>    a worktree of the default branch
+
+Remote branches are fetched into origin/*, which a plain bare clone skips.
+git and wt commands work from the hub directory as well as from the
+worktrees. The directory defaults to the repository name of the URL.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runClone,
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	url := args[0]
+
+	dir := repoNameFromURL(url)
+	if len(args) > 1 {
+		dir = args[1]
+	}
+	if dir == "" {
+		return fmt.Errorf("cannot derive a directory name from %s, pass one", url)
+	}
+	hub, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(hub); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", hub)
+	}
+
+	fmt.Printf("Cloning into hub: %s\n", hub)
+	repo, err := git.CloneHub(cmd.Context(), url, hub, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	// Worktrees go next to .bare, named after their branch
+	cfg := config.DefaultConfig()
+	cfg.Worktree.Basedir = "."
+	cfg.Worktree.Naming = "{branch}"
+	configPath := filepath.Join(hub, config.ConfigFileName)
+	if err := cfg.Save(configPath); err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+	fmt.Printf("Created %s\n", configPath)
+
+	branch, err := repo.DefaultBranch()
+	if err != nil || !repo.BranchExists(branch) {
+		fmt.Println("\nThe repository has no branches yet, create a worktree with wt add -b.")
+		return nil
+	}
+
+	basedir, err := cfg.GetWorktreeBasedir(repo.MainPath)
+	if err != nil {
+		return fmt.Errorf("failed to get basedir: %w", err)
+	}
+	worktreePath := filepath.Join(basedir, cfg.GenerateWorktreeName(repo.Name, branch))
+
+	fmt.Printf("Creating worktree at: %s\n", worktreePath)
+	if err := git.NewManager(repo).Add(worktreePath, branch, false, false); err != nil {
+		return err
+	}
+
+	changed, err := shell.RequestCd(worktreePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nRepository cloned successfully!\n")
+	if !changed {
+		fmt.Printf("  cd %s\n", worktreePath)
+	}
+	return nil
+}
+
+// repoNameFromURL returns the repository name at the end of a clone URL
+// such as https://host/owner/name.git or git@host:owner/name
+func repoNameFromURL(url string) string {
+	url = strings.TrimRight(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	return url
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/git"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <shell>",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for bash, zsh, fish or powershell.
+
+Completions include local and remote branches for wt add, and existing
+worktrees (described by their path) for wt remove, select, move and lock.
+
+  bash:        source <(wt completion bash)
+  zsh:         wt completion zsh > "${fpath[1]}/_wt"
+  fish:        wt completion fish > ~/.config/fish/completions/wt.fish
+  powershell:  wt completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Args:      cobra.ExactArgs(1),
+	RunE:      runCompletion,
+}
+
+func init() {
+	// Replace cobra's default completion command with our own
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+// completeBranches completes a single local or remote branch name
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	repo, err := detectRepositoryForReading(cmd.Context())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	branches, _ := repo.ListBranches()
+	isLocal := make(map[string]bool, len(branches))
+	var completions []string
+	for _, b := range branches {
+		isLocal[b] = true
+		completions = append(completions, b+"\tlocal")
+	}
+
+	remoteBranches, _ := repo.ListRemoteBranches()
+	for _, b := range remoteBranches {
+		if !isLocal[b.Name] {
+			completions = append(completions, b.Ref()+"\tremote")
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLocalBranches completes local branch names, e.g. for flag values
+func completeLocalBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	repo, err := detectRepositoryForReading(cmd.Context())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	branches, _ := repo.ListBranches()
+	return branches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRemotes completes remote names
+func completeRemotes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	repo, err := detectRepositoryForReading(cmd.Context())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	remotes, _ := repo.ListRemotes()
+	return remotes, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorktrees completes worktree branches described by their path.
+// Worktrees already named in args are left out.
+func completeWorktrees(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return worktreeCompletions(cmd.Context(), args, false), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLinkedWorktrees completes like completeWorktrees without the main worktree
+func completeLinkedWorktrees(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return worktreeCompletions(cmd.Context(), args, true), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOneWorktree completes a single worktree argument
+func completeOneWorktree(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeWorktrees(cmd, args, toComplete)
+}
+
+// completeOneLinkedWorktree completes a single linked worktree argument
+func completeOneLinkedWorktree(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeLinkedWorktrees(cmd, args, toComplete)
+}
+
+func worktreeCompletions(ctx context.Context, args []string, linkedOnly bool) []string {
+	repo, err := detectRepositoryForReading(ctx)
+	if err != nil {
+		return nil
+	}
+
+	worktrees, err := git.NewManager(repo).List()
+	if err != nil {
+		return nil
+	}
+
+	used := toSet(args)
+	var completions []string
+	for i, wt := range worktrees {
+		if (linkedOnly && i == 0) || wt.IsBare || wt.Branch == "" || wt.Branch == "(detached)" || used[wt.Branch] {
+			continue
+		}
+		completions = append(completions, wt.Branch+"\t"+wt.Path)
+	}
+	return completions
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+)
+
+var (
+	configShowOrigin bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect wt configuration",
+	Long: `Inspect the effective wt configuration.
+
+Configuration is merged field by field from, in increasing priority:
+  - the user config ($XDG_CONFIG_HOME/wt/config.json)
+  - the repository .wt.json
+  - the untracked .wt.local.json next to it`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Print the effective configuration as JSON.
+
+Use --origin to list each value with the file it came from.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&configShowOrigin, "origin", false, "Show where each value came from")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	// Outside a repository only the user config applies
+	startDir, mainDir := ".", ""
+	if repo, err := git.DetectRepository(cmd.Context()); err == nil {
+		startDir, mainDir = repo.RootPath, repo.MainPath
+	}
+
+	cfg, origins, err := config.LoadWithOrigins(startDir, mainDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !configShowOrigin {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	}
+
+	keys, values, err := cfg.Flatten()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tORIGIN")
+	fmt.Fprintln(w, "---\t-----\t------")
+
+	for _, key := range keys {
+		origin := "default"
+		if layer, ok := origins[key]; ok {
+			origin = layer.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, values[key], origin)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/setup"
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+var (
+	doctorFix  bool
+	doctorJSON bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check worktrees and setup for problems",
+	Long: `Inspect the repository for broken worktree state:
+
+  - invalid configuration files
+  - worktrees on disk that git doesn't know about, or whose link to the
+    repository is broken
+  - registered worktrees that are missing on disk (locked ones are not
+    pruned by --fix)
+  - setup links that are missing or dangling
+  - rendered setup templates that were changed since setup
+  - worktree paths that don't match the naming template
+
+Use --fix to repair what can be repaired automatically. The command exits
+with an error while errors or warnings remain, which makes it usable in CI.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair fixable problems")
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output the report in JSON format")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// Issue severities
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// doctorIssue is a problem found by wt doctor
+type doctorIssue struct {
+	Severity string
+	Check    string
+	Path     string
+	Message  string
+	Fixable  bool
+	Fixed    bool
+	FixError string `json:",omitempty"`
+
+	fix func() error
+}
+
+// doctorReport is the JSON output of wt doctor
+type doctorReport struct {
+	Issues   []doctorIssue
+	Errors   int
+	Warnings int
+	Infos    int
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	var issues []doctorIssue
+	add := func(issue doctorIssue) {
+		issue.Fixable = issue.fix != nil
+		issues = append(issues, issue)
+	}
+
+	// Configuration
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
+	if err != nil {
+		add(doctorIssue{Severity: severityError, Check: "config", Path: repo.RootPath, Message: err.Error()})
+		cfg = config.DefaultConfig()
+	}
+
+	manager := git.NewManager(repo)
+	worktrees, err := manager.List()
+	if err != nil {
+		return err
+	}
+	if len(worktrees) == 0 {
+		return fmt.Errorf("no worktrees found")
+	}
+	main := worktrees[0]
+
+	commonDir, err := repo.GetCommonDir()
+	if err != nil {
+		return err
+	}
+	adminDir := filepath.Join(commonDir, "worktrees")
+
+	registered := make(map[string]bool, len(worktrees))
+	for _, wt := range worktrees {
+		registered[wt.Path] = true
+	}
+
+	// Registered worktrees whose link to the repository is broken
+	for _, wt := range worktrees[1:] {
+		if wt.IsBare || !util.IsDirectory(wt.Path) {
+			continue
+		}
+		gitDir, err := git.ReadGitFile(wt.Path)
+		if err == nil && util.IsDirectory(gitDir) {
+			continue
+		}
+		path := wt.Path
+		add(doctorIssue{
+			Severity: severityError,
+			Check:    "repository-link",
+			Path:     path,
+			Message:  "worktree is not linked to the repository",
+			fix:      func() error { return manager.Repair(path) },
+		})
+	}
+
+	// Worktrees on disk that git doesn't know about
+	if basedir, err := cfg.GetWorktreeBasedir(repo.MainPath); err == nil {
+		entries, _ := os.ReadDir(basedir)
+		for _, entry := range entries {
+			path := filepath.Join(basedir, entry.Name())
+			if !entry.IsDir() || registered[path] {
+				continue
+			}
+			gitDir, err := git.ReadGitFile(path)
+			if err != nil || !strings.HasPrefix(gitDir, adminDir+string(filepath.Separator)) {
+				continue
+			}
+			add(doctorIssue{
+				Severity: severityError,
+				Check:    "unregistered",
+				Path:     path,
+				Message:  "worktree of this repository is not registered with git",
+				fix:      func() error { return manager.Repair(path) },
+			})
+		}
+	}
+
+	// Registered worktrees missing on disk, pruned together
+	var pruneOnce sync.Once
+	var pruneErr error
+	prune := func() error {
+		pruneOnce.Do(func() { _, pruneErr = manager.Prune(false) })
+		return pruneErr
+	}
+	for _, wt := range worktrees {
+		if !wt.IsPrunable && util.IsDirectory(wt.Path) {
+			continue
+		}
+		if wt.IsLocked {
+			// Locked worktrees are kept by prune, often on removable media
+			message := "registered worktree is missing on disk but locked"
+			if wt.LockReason != "" {
+				message += " (" + wt.LockReason + ")"
+			}
+			add(doctorIssue{
+				Severity: severityWarning,
+				Check:    "missing",
+				Path:     wt.Path,
+				Message:  message + "; run 'git worktree unlock' first if it is gone for good",
+			})
+			continue
+		}
+		add(doctorIssue{
+			Severity: severityWarning,
+			Check:    "missing",
+			Path:     wt.Path,
+			Message:  "registered worktree is missing on disk",
+			fix:      prune,
+		})
+	}
+
+	// Setup links
+	source, err := setupSource(repo, manager, cfg)
+	if err != nil {
+		add(doctorIssue{Severity: severityError, Check: "config", Path: repo.RootPath, Message: err.Error()})
+		source = main.Path
+	}
+	if links := cfg.Setup.LinkEntries(); len(links) > 0 {
+		for _, wt := range worktrees {
+			if wt.Path == source || wt.IsBare || !util.IsDirectory(wt.Path) {
+				continue
+			}
+			linkIssues, err := setup.CheckLinks(source, wt.Path, config.Patterns(links))
+			if err != nil {
+				add(doctorIssue{Severity: severityError, Check: "config", Path: wt.Path, Message: err.Error()})
+				break
+			}
+			for _, li := range linkIssues {
+				add(linkDoctorIssue(cmd.Context(), source, wt.Path, li, cfg.Setup.RelativeLinks))
+			}
+		}
+	}
+
+	// Rendered templates changed since setup
+	for _, wt := range worktrees[1:] {
+		if wt.IsBare || !util.IsDirectory(wt.Path) {
+			continue
+		}
+		record, err := setup.LoadRecord(wt.Path)
+		if err != nil {
+			continue
+		}
+		for _, p := range record.ModifiedTemplates(wt.Path) {
+			add(doctorIssue{
+				Severity: severityInfo,
+				Check:    "template",
+				Path:     filepath.Join(wt.Path, p),
+				Message:  "rendered template was modified or deleted since setup",
+			})
+		}
+	}
+
+	// Naming
+	if basedir, err := cfg.GetWorktreeBasedir(repo.MainPath); err == nil {
+		for _, wt := range worktrees[1:] {
+			if wt.Branch == "" || wt.Branch == "(detached)" {
+				continue
+			}
+			expected := filepath.Join(basedir, cfg.GenerateWorktreeName(repo.Name, wt.Branch))
+			if expected != wt.Path {
+				add(doctorIssue{
+					Severity: severityInfo,
+					Check:    "naming",
+					Path:     wt.Path,
+					Message:  fmt.Sprintf("path does not match naming template (expected %s, use wt move %s)", expected, wt.Branch),
+				})
+			}
+		}
+	}
+
+	if doctorFix {
+		for i := range issues {
+			if issues[i].fix == nil {
+				continue
+			}
+			if err := issues[i].fix(); err != nil {
+				issues[i].FixError = err.Error()
+			} else {
+				issues[i].Fixed = true
+			}
+		}
+	}
+
+	report := doctorReport{Issues: issues}
+	if report.Issues == nil {
+		report.Issues = []doctorIssue{}
+	}
+	unresolved := 0
+	for _, issue := range issues {
+		switch issue.Severity {
+		case severityError:
+			report.Errors++
+		case severityWarning:
+			report.Warnings++
+		case severityInfo:
+			report.Infos++
+		}
+		if issue.Severity != severityInfo && !issue.Fixed {
+			unresolved++
+		}
+	}
+
+	if doctorJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	if unresolved > 0 {
+		return fmt.Errorf("%d problem(s) need attention", unresolved)
+	}
+	return nil
+}
+
+// linkDoctorIssue converts a setup link problem into a doctor issue
+func linkDoctorIssue(ctx context.Context, srcBase, dstBase string, li setup.LinkIssue, relative bool) doctorIssue {
+	dst := filepath.Join(dstBase, li.Path)
+	issue := doctorIssue{
+		Severity: severityWarning,
+		Check:    "link",
+		Path:     dst,
+	}
+
+	switch {
+	case li.Problem == setup.LinkMissing:
+		issue.Message = "setup link is missing"
+	case li.SourceExists:
+		issue.Message = "setup link points to a path that does not exist"
+	default:
+		issue.Message = "setup link is dangling and its source no longer exists"
+		issue.fix = func() error { return os.Remove(dst) }
+		return issue
+	}
+
+	issue.fix = func() error {
+		return setup.LinkPaths(ctx, srcBase, dstBase, config.Entries(li.Path), relative, true)
+	}
+	return issue
+}
+
+// printDoctorReport prints the doctor issues as a table
+func printDoctorReport(report doctorReport) {
+	if len(report.Issues) == 0 {
+		fmt.Println("No problems found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tCHECK\tPATH\tPROBLEM")
+	fmt.Fprintln(w, "--------\t-----\t----\t-------")
+
+	fixable := false
+	for _, issue := range report.Issues {
+		if issue.Fixable && !issue.Fixed {
+			fixable = true
+		}
+
+		message := issue.Message
+		switch {
+		case issue.Fixed:
+			message += " [fixed]"
+		case issue.FixError != "":
+			message += " [fix failed: " + issue.FixError + "]"
+		case issue.Fixable && !doctorFix:
+			message += " [fixable]"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Severity, issue.Check, issue.Path, message)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\n%d error(s), %d warning(s), %d info\n", report.Errors, report.Warnings, report.Infos)
+	if fixable && !doctorFix {
+		fmt.Println("Run 'wt doctor --fix' to repair fixable problems.")
+	}
+}
@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/git"
+)
+
+var (
+	execFilter   string
+	execParallel int
+	execFailFast bool
+	execJSON     bool
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command in every worktree",
+	Long: `Run a command in each worktree directory.
+
+Output lines are prefixed with the worktree branch and a summary of exit
+codes is printed at the end. Use --filter to select worktrees by branch
+(a glob pattern, or a substring when no wildcards are given).`,
+	Example: `  wt exec -- git pull
+  wt exec --parallel 4 --filter 'feature/*' -- go test ./...`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	execCmd.Flags().StringVar(&execFilter, "filter", "", "Only run in worktrees whose branch matches the pattern")
+	execCmd.Flags().IntVarP(&execParallel, "parallel", "p", 1, "Number of worktrees to run in parallel")
+	execCmd.Flags().BoolVar(&execFailFast, "fail-fast", false, "Stop after the first failing command")
+	execCmd.Flags().BoolVar(&execJSON, "json", false, "Output results in JSON format")
+	// Everything after the command name belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.RegisterFlagCompletionFunc("filter", completeWorktrees)
+	rootCmd.AddCommand(execCmd)
+}
+
+// execResult is the outcome of running the command in one worktree
+type execResult struct {
+	Branch   string
+	Path     string
+	ExitCode int
+	Duration string
+	Skipped  bool   `json:",omitempty"`
+	Error    string `json:",omitempty"`
+	Output   string `json:",omitempty"`
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	manager := git.NewManager(repo)
+	worktrees, err := manager.List()
+	if err != nil {
+		return err
+	}
+
+	var targets []git.Worktree
+	for _, wt := range worktrees {
+		if wt.IsBare || wt.IsPrunable {
+			continue
+		}
+		if execFilter != "" && !matchBranch(execFilter, wt.Branch) {
+			continue
+		}
+		targets = append(targets, wt)
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("no worktrees match")
+	}
+
+	parallel := execParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	results := make([]execResult, len(targets))
+	sem := make(chan struct{}, parallel)
+	var stdoutMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, wt := range targets {
+		// Acquire before spawning so worktrees start in list order
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, wt git.Worktree) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			label := worktreeLabel(wt)
+			res := execResult{Branch: label, Path: wt.Path}
+
+			if ctx.Err() != nil {
+				res.Skipped = true
+				res.ExitCode = -1
+				res.Duration = "-"
+				results[i] = res
+				return
+			}
+
+			c := exec.CommandContext(ctx, args[0], args[1:]...)
+			c.Dir = wt.Path
+
+			var buf bytes.Buffer
+			var out, errOut io.Writer
+			if execJSON {
+				out, errOut = &buf, &buf
+			} else {
+				prefix := fmt.Sprintf("[%s] ", label)
+				pw := &prefixWriter{w: os.Stdout, mu: &stdoutMu, prefix: prefix}
+				pwErr := &prefixWriter{w: os.Stderr, mu: &stdoutMu, prefix: prefix}
+				defer pw.Flush()
+				defer pwErr.Flush()
+				out, errOut = pw, pwErr
+			}
+			c.Stdout = out
+			c.Stderr = errOut
+
+			start := time.Now()
+			err := c.Run()
+			res.Duration = time.Since(start).Round(time.Millisecond).String()
+			res.Output = buf.String()
+
+			if err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					res.ExitCode = exitErr.ExitCode()
+				} else {
+					res.ExitCode = -1
+					res.Error = err.Error()
+				}
+				if execFailFast {
+					cancel()
+				}
+			}
+			results[i] = res
+		}(i, wt)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 && !r.Skipped {
+			failed++
+		}
+	}
+
+	if execJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printExecSummary(results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d of %d worktree(s)", failed, len(results))
+	}
+	return nil
+}
+
+// printExecSummary prints the exit code of each worktree as a table
+func printExecSummary(results []execResult) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tEXIT\tDURATION\tPATH")
+	fmt.Fprintln(w, "------\t----\t--------\t----")
+
+	for _, r := range results {
+		exit := fmt.Sprintf("%d", r.ExitCode)
+		switch {
+		case r.Skipped:
+			exit = "skipped"
+		case r.Error != "":
+			exit = "error: " + r.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Branch, exit, r.Duration, r.Path)
+	}
+
+	w.Flush()
+}
+
+// matchBranch matches a branch against a glob pattern, or a substring
+// when the pattern has no wildcards
+func matchBranch(pattern, branch string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := path.Match(pattern, branch)
+		return ok
+	}
+	return strings.Contains(branch, pattern)
+}
+
+// worktreeLabel returns the branch name, or the short commit for detached worktrees
+func worktreeLabel(wt git.Worktree) string {
+	if wt.Branch != "" && wt.Branch != "(detached)" {
+		return wt.Branch
+	}
+	if len(wt.Head) >= 7 {
+		return wt.Head[:7]
+	}
+	return wt.Path
+}
+
+// prefixWriter writes each complete line to w with a prefix.
+// Writers sharing mu never interleave within a line.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes any trailing partial line
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	io.WriteString(p.w, p.prefix)
+	p.w.Write(line)
+}
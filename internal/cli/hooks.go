@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/hooks"
+	"github.com/superkoh/worktree-manager/internal/resources"
+	"github.com/superkoh/worktree-manager/internal/setup"
+)
+
+// newHookContext builds the hook context for a worktree
+func newHookContext(repo *git.Repository, manager *git.Manager, path, branch string) hooks.Context {
+	ctx := hooks.Context{
+		WorktreePath: path,
+		Branch:       branch,
+		RepoRoot:     repo.RootPath,
+	}
+	if main, err := manager.GetMainWorktree(); err == nil {
+		ctx.MainWorktree = main.Path
+	}
+	if commonDir, err := repo.GetCommonDir(); err == nil {
+		if registry, err := resources.Load(commonDir); err == nil {
+			ctx.Ports = registry.Ports[path]
+		}
+	}
+	return ctx
+}
+
+// newTemplateData builds the setup template data for a worktree
+func newTemplateData(repo *git.Repository, ctx hooks.Context) *setup.TemplateData {
+	return setup.NewTemplateData(ctx.Branch, repo.Name, ctx.WorktreePath, ctx.MainWorktree, ctx.Ports)
+}
+
+// setupSource returns the worktree setup copies, renders and links from.
+// By setup.source it is the main worktree, the current one, or a worktree
+// named by branch or path. A bare repository has no files, so the worktree
+// of its default branch stands in for the main worktree.
+func setupSource(repo *git.Repository, manager *git.Manager, cfg *config.Config) (string, error) {
+	switch cfg.Setup.Source {
+	case "", config.SourceMain:
+		main, err := manager.GetMainWorktree()
+		if err != nil {
+			return "", err
+		}
+		if !main.IsBare {
+			return main.Path, nil
+		}
+		if branch, err := repo.DefaultBranch(); err == nil {
+			if wt, err := manager.FindByBranch(branch); err == nil && wt != nil {
+				return wt.Path, nil
+			}
+		}
+		return repo.RootPath, nil
+	case config.SourceCurrent:
+		return repo.RootPath, nil
+	}
+
+	wt, err := findWorktree(manager, cfg.Setup.Source)
+	if err != nil {
+		return "", fmt.Errorf("setup.source: %w", err)
+	}
+	return wt.Path, nil
+}
+
+// allocateResources reserves the configured ports for a new worktree
+func allocateResources(repo *git.Repository, cfg *config.Config, path string) (map[string]int, error) {
+	if len(cfg.Resources.Ports) == 0 {
+		return nil, nil
+	}
+
+	commonDir, err := repo.GetCommonDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var ports map[string]int
+	err = resources.Update(commonDir, func(r *resources.Registry) error {
+		ports, err = r.AllocatePorts(path, cfg.Resources.Ports)
+		return err
+	})
+	return ports, err
+}
+
+// updateResources applies fn to the resource registry and saves it. It does
+// nothing when no resources were ever allocated, even if ports are
+// configured now. Failures only print a warning since the worktree change
+// already happened.
+func updateResources(repo *git.Repository, fn func(*resources.Registry)) {
+	commonDir, err := repo.GetCommonDir()
+	if err == nil {
+		if _, statErr := os.Stat(resources.RegistryPath(commonDir)); os.IsNotExist(statErr) {
+			return
+		}
+		err = resources.Update(commonDir, func(r *resources.Registry) error {
+			if len(r.Ports) > 0 {
+				fn(r)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update resource registry: %v\n", err)
+	}
+}
@@ -27,7 +27,7 @@ func init() {
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
-	repo, err := git.DetectRepository()
+	repo, err := git.DetectRepository(cmd.Context())
 	if err != nil {
 		return err
 	}
@@ -40,8 +40,8 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	cfg := config.DefaultConfig()
 	// Add some sensible defaults for common use cases
-	cfg.Setup.Copy = []string{".env", ".env.local"}
-	cfg.Setup.Link = []string{"node_modules", "vendor"}
+	cfg.Setup.Copy = config.Entries(".env", ".env.local")
+	cfg.Setup.Link = config.Entries("node_modules", "vendor")
 
 	if err := cfg.Save(configPath); err != nil {
 		return fmt.Errorf("failed to create config: %w", err)
@@ -53,6 +53,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Println("  - worktree.naming: naming template ({repo}-{branch})")
 	fmt.Println("  - setup.copy: files to copy to new worktrees")
 	fmt.Println("  - setup.link: paths to symlink to new worktrees")
+	fmt.Printf("\nPersonal overrides can go in an untracked %s.\n", config.LocalConfigFileName)
 
 	return nil
 }
@@ -4,31 +4,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/superkoh/worktree-manager/internal/git"
 )
 
 var (
-	listJSON bool
+	listJSON   bool
+	listStatus bool
 )
 
 var listCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List all worktrees",
-	Long:    `List all worktrees in the current repository.`,
-	RunE:    runList,
+	Long: `List all worktrees in the current repository.
+
+Use --status to show changes, upstream sync state and the last commit
+for each worktree.`,
+	RunE: runList,
 }
 
 func init() {
 	listCmd.Flags().BoolVar(&listJSON, "json", false, "Output in JSON format")
+	listCmd.Flags().BoolVarP(&listStatus, "status", "s", false, "Show git status of each worktree")
 	rootCmd.AddCommand(listCmd)
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	repo, err := git.DetectRepository()
+	repo, err := detectRepositoryForReading(cmd.Context())
 	if err != nil {
 		return err
 	}
@@ -39,6 +46,10 @@ func runList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if listStatus {
+		manager.LoadStatus(worktrees)
+	}
+
 	if listJSON {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -47,8 +58,13 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	// Table output
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "BRANCH\tPATH\tSTATUS")
-	fmt.Fprintln(w, "------\t----\t------")
+	if listStatus {
+		fmt.Fprintln(w, "BRANCH\tPATH\tSTATUS\tCHANGES\tSYNC\tLAST COMMIT")
+		fmt.Fprintln(w, "------\t----\t------\t-------\t----\t-----------")
+	} else {
+		fmt.Fprintln(w, "BRANCH\tPATH\tSTATUS")
+		fmt.Fprintln(w, "------\t----\t------")
+	}
 
 	for _, wt := range worktrees {
 		status := ""
@@ -56,6 +72,9 @@ func runList(cmd *cobra.Command, args []string) error {
 			status = "* current"
 		} else if wt.IsLocked {
 			status = "locked"
+			if wt.LockReason != "" {
+				status += " (" + wt.LockReason + ")"
+			}
 		} else if wt.IsPrunable {
 			status = "prunable"
 		} else if wt.IsBare {
@@ -63,12 +82,83 @@ func runList(cmd *cobra.Command, args []string) error {
 		}
 
 		branch := wt.Branch
-		if branch == "" {
+		if wt.IsBare {
+			branch = "(bare)"
+		} else if branch == "" && len(wt.Head) >= 7 {
 			branch = wt.Head[:7] // Show short commit hash
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\n", branch, wt.Path, status)
+		if listStatus {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", branch, wt.Path, status,
+				formatChanges(wt.Status), formatSync(wt.Status), formatLastCommit(wt.Status))
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", branch, wt.Path, status)
+		}
 	}
 
 	return w.Flush()
 }
+
+// formatChanges renders staged/unstaged/untracked counts
+func formatChanges(s *git.WorktreeStatus) string {
+	if s == nil {
+		return "-"
+	}
+	if !s.IsDirty() {
+		return "clean"
+	}
+
+	var parts []string
+	if s.Staged > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", s.Staged))
+	}
+	if s.Unstaged > 0 {
+		parts = append(parts, fmt.Sprintf("~%d", s.Unstaged))
+	}
+	if s.Untracked > 0 {
+		parts = append(parts, fmt.Sprintf("?%d", s.Untracked))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatSync renders ahead/behind counts relative to the upstream
+func formatSync(s *git.WorktreeStatus) string {
+	if s == nil || s.Upstream == "" {
+		return "-"
+	}
+	if s.Ahead == 0 && s.Behind == 0 {
+		return "up to date"
+	}
+	return fmt.Sprintf("↑%d ↓%d", s.Ahead, s.Behind)
+}
+
+// formatLastCommit renders the last commit subject and age
+func formatLastCommit(s *git.WorktreeStatus) string {
+	if s == nil || s.LastCommitTime.IsZero() {
+		return "-"
+	}
+
+	subject := s.LastCommitSubject
+	if runes := []rune(subject); len(runes) > 50 {
+		subject = string(runes[:47]) + "..."
+	}
+	return fmt.Sprintf("%s (%s)", subject, formatAge(time.Since(s.LastCommitTime)))
+}
+
+// formatAge renders a duration as a short relative age
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+	}
+}
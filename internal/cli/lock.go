@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/tui"
+)
+
+var (
+	lockReason string
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock [worktree]",
+	Short: "Lock a worktree",
+	Long: `Lock a worktree, given as a path or branch name, so that it cannot be
+pruned, moved or removed.
+
+If no worktree is specified, an interactive selector will be shown.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeOneLinkedWorktree,
+	RunE:              runLock,
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock [worktree]",
+	Short: "Unlock a worktree",
+	Long: `Unlock a locked worktree, given as a path or branch name.
+
+If no worktree is specified, an interactive selector will be shown.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeOneLinkedWorktree,
+	RunE:              runUnlock,
+}
+
+func init() {
+	lockCmd.Flags().StringVar(&lockReason, "reason", "", "Reason for locking the worktree")
+	rootCmd.AddCommand(lockCmd)
+	rootCmd.AddCommand(unlockCmd)
+}
+
+func runLock(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	manager := git.NewManager(repo)
+	wt, err := pickWorktree(manager, args, func(wt git.Worktree) bool { return !wt.IsLocked })
+	if err != nil || wt == nil {
+		return err
+	}
+
+	if wt.IsLocked {
+		return fmt.Errorf("worktree %s is already locked", wt.Path)
+	}
+
+	if err := manager.Lock(wt.Path, lockReason); err != nil {
+		return err
+	}
+
+	fmt.Printf("Locked: %s\n", wt.Path)
+	return nil
+}
+
+func runUnlock(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	manager := git.NewManager(repo)
+	wt, err := pickWorktree(manager, args, func(wt git.Worktree) bool { return wt.IsLocked })
+	if err != nil || wt == nil {
+		return err
+	}
+
+	if !wt.IsLocked {
+		return fmt.Errorf("worktree %s is not locked", wt.Path)
+	}
+
+	if err := manager.Unlock(wt.Path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unlocked: %s\n", wt.Path)
+	return nil
+}
+
+// pickWorktree resolves the worktree named in args, or shows a selector of
+// linked worktrees accepted by filter. It returns nil if nothing was chosen.
+func pickWorktree(manager *git.Manager, args []string, filter func(git.Worktree) bool) (*git.Worktree, error) {
+	if len(args) > 0 {
+		return findWorktree(manager, args[0])
+	}
+
+	worktrees, err := manager.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// The main worktree is always first and cannot be locked
+	var items []tui.Item
+	for i, wt := range worktrees {
+		if i == 0 || !filter(wt) {
+			continue
+		}
+		items = append(items, tui.Item{
+			Name:        wt.Branch,
+			Path:        wt.Path,
+			Description: worktreeDescription(wt),
+			IsCurrent:   wt.IsCurrent,
+		})
+	}
+
+	if len(items) == 0 {
+		fmt.Println("No matching worktrees.")
+		return nil, nil
+	}
+
+	selected, err := tui.SelectWorktree(items)
+	if err != nil || selected == nil {
+		return nil, err
+	}
+	return findWorktree(manager, selected.Path)
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/resources"
+	"github.com/superkoh/worktree-manager/internal/setup"
+	"github.com/superkoh/worktree-manager/internal/shell"
+)
+
+var (
+	moveRenameBranch string
+	movePrintPath    bool
+)
+
+var moveCmd = &cobra.Command{
+	Use:     "move <worktree> [new-path]",
+	Aliases: []string{"mv"},
+	Short:   "Move or rename a worktree",
+	Long: `Move a worktree, given as a path or branch name, to a new location.
+
+If no new path is given, the path is regenerated from the naming template,
+which is useful after changing worktree.naming. Use --rename-branch to
+also rename the branch; the new path is then derived from the new name.
+
+Symlinks created by setup that point into the old location are updated.`,
+	Args: cobra.RangeArgs(1, 2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeLinkedWorktrees(cmd, args, toComplete)
+		}
+		if len(args) == 1 {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: runMove,
+}
+
+func init() {
+	moveCmd.Flags().StringVar(&moveRenameBranch, "rename-branch", "", "Rename the worktree branch")
+	moveCmd.Flags().BoolVar(&movePrintPath, "print-path", false, "Print the new worktree path (for shell integration)")
+	moveCmd.RegisterFlagCompletionFunc("rename-branch", cobra.NoFileCompletions)
+	rootCmd.AddCommand(moveCmd)
+}
+
+func runMove(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager := git.NewManager(repo)
+	wt, err := findWorktree(manager, args[0])
+	if err != nil {
+		return err
+	}
+
+	main, err := manager.GetMainWorktree()
+	if err != nil {
+		return err
+	}
+	if wt.Path == main.Path {
+		return fmt.Errorf("the main worktree cannot be moved")
+	}
+
+	branch := wt.Branch
+	if moveRenameBranch != "" {
+		if branch == "" || branch == "(detached)" {
+			return fmt.Errorf("worktree %s has no branch to rename", wt.Path)
+		}
+		if moveRenameBranch != wt.Branch {
+			if err := repo.CheckBranchName(moveRenameBranch); err != nil {
+				return err
+			}
+			if repo.BranchExists(moveRenameBranch) {
+				return fmt.Errorf("branch '%s' already exists", moveRenameBranch)
+			}
+		}
+		branch = moveRenameBranch
+	}
+
+	var newPath string
+	if len(args) > 1 {
+		newPath, err = filepath.Abs(args[1])
+		if err != nil {
+			return err
+		}
+	} else {
+		basedir, err := cfg.GetWorktreeBasedir(repo.MainPath)
+		if err != nil {
+			return fmt.Errorf("failed to get basedir: %w", err)
+		}
+		newPath = filepath.Join(basedir, cfg.GenerateWorktreeName(repo.Name, branch))
+	}
+
+	oldPath := wt.Path
+	if newPath == oldPath && moveRenameBranch == "" {
+		if !movePrintPath {
+			fmt.Printf("Worktree is already at %s\n", newPath)
+		} else {
+			fmt.Println(newPath)
+		}
+		return nil
+	}
+
+	// Run git from the main worktree when moving the current one
+	if oldPath == repo.RootPath {
+		if repo, err = git.DetectRepositoryFrom(cmd.Context(), main.Path); err != nil {
+			return err
+		}
+		manager = git.NewManager(repo)
+	}
+
+	if newPath != oldPath {
+		if !movePrintPath {
+			fmt.Printf("Moving worktree: %s -> %s\n", oldPath, newPath)
+		}
+		if err := manager.Move(oldPath, newPath); err != nil {
+			return err
+		}
+		updateResources(repo, func(r *resources.Registry) { r.Rename(oldPath, newPath) })
+	}
+
+	if moveRenameBranch != "" && moveRenameBranch != wt.Branch {
+		if !movePrintPath {
+			fmt.Printf("Renaming branch: %s -> %s\n", wt.Branch, moveRenameBranch)
+		}
+		if err := manager.RenameBranch(newPath, wt.Branch, moveRenameBranch); err != nil {
+			// Don't leave the worktree moved under a name it doesn't have
+			if newPath != oldPath {
+				if undoErr := manager.Move(newPath, oldPath); undoErr != nil {
+					return fmt.Errorf("%w (moving the worktree back also failed: %v)", err, undoErr)
+				}
+				updateResources(repo, func(r *resources.Registry) { r.Rename(newPath, oldPath) })
+			}
+			return err
+		}
+	}
+
+	// Repair setup symlinks that point into the old location
+	if links := cfg.Setup.LinkEntries(); newPath != oldPath && len(links) > 0 {
+		worktrees, err := manager.List()
+		if err != nil {
+			return err
+		}
+		for _, other := range worktrees {
+			if other.IsBare || other.IsPrunable {
+				continue
+			}
+			if _, err := setup.RetargetLinks(other.Path, config.Patterns(links), oldPath, newPath, movePrintPath); err != nil {
+				if !movePrintPath {
+					fmt.Printf("Warning: failed to repair links in %s: %v\n", other.Path, err)
+				}
+			}
+		}
+	}
+
+	// Follow the worktree if the shell is inside it
+	if wt.IsCurrent {
+		if _, err := shell.RequestCd(newPath); err != nil {
+			return err
+		}
+	}
+
+	if movePrintPath {
+		fmt.Println(newPath)
+	} else {
+		fmt.Println("Done!")
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/resources"
+)
+
+var (
+	portsJSON bool
+)
+
+var portsCmd = &cobra.Command{
+	Use:   "ports [worktree]",
+	Short: "List ports allocated to worktrees",
+	Long: `List the ports allocated to worktrees from the resources.ports ranges
+in .wt.json.
+
+Ports are allocated by wt add and freed by wt remove. Allocations of
+worktrees that no longer exist are marked stale and freed by wt prune.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeOneLinkedWorktree,
+	RunE:              runPorts,
+}
+
+func init() {
+	portsCmd.Flags().BoolVar(&portsJSON, "json", false, "Output in JSON format")
+	rootCmd.AddCommand(portsCmd)
+}
+
+// portAllocation is a port held by a worktree
+type portAllocation struct {
+	Path   string
+	Branch string `json:",omitempty"`
+	Name   string
+	Port   int
+	Stale  bool `json:",omitempty"`
+}
+
+func runPorts(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	manager := git.NewManager(repo)
+	worktrees, err := manager.List()
+	if err != nil {
+		return err
+	}
+
+	commonDir, err := repo.GetCommonDir()
+	if err != nil {
+		return err
+	}
+	registry, err := resources.Load(commonDir)
+	if err != nil {
+		return err
+	}
+
+	only := ""
+	if len(args) > 0 {
+		wt, err := findWorktree(manager, args[0])
+		if err != nil {
+			return err
+		}
+		only = wt.Path
+	}
+
+	branches := make(map[string]string, len(worktrees))
+	for _, wt := range worktrees {
+		branches[wt.Path] = wt.Branch
+	}
+
+	allocations := []portAllocation{}
+	for path, ports := range registry.Ports {
+		if only != "" && path != only {
+			continue
+		}
+		branch, registered := branches[path]
+		for name, port := range ports {
+			allocations = append(allocations, portAllocation{
+				Path:   path,
+				Branch: branch,
+				Name:   name,
+				Port:   port,
+				Stale:  !registered,
+			})
+		}
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Port < allocations[j].Port
+	})
+
+	if portsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(allocations)
+	}
+
+	if len(allocations) == 0 {
+		fmt.Println("No ports allocated.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tNAME\tBRANCH\tPATH")
+	fmt.Fprintln(w, "----\t----\t------\t----")
+
+	for _, a := range allocations {
+		branch := a.Branch
+		if a.Stale {
+			branch = "(stale)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", a.Port, a.Name, branch, a.Path)
+	}
+
+	return w.Flush()
+}
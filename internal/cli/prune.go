@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/resources"
 )
 
 var (
@@ -26,7 +27,7 @@ func init() {
 }
 
 func runPrune(cmd *cobra.Command, args []string) error {
-	repo, err := git.DetectRepository()
+	repo, err := git.DetectRepository(cmd.Context())
 	if err != nil {
 		return err
 	}
@@ -53,5 +54,20 @@ func runPrune(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Free resources of worktrees git no longer knows about
+	if !pruneDryRun {
+		if worktrees, err := manager.List(); err == nil {
+			registered := make(map[string]bool, len(worktrees))
+			for _, wt := range worktrees {
+				registered[wt.Path] = true
+			}
+			updateResources(repo, func(r *resources.Registry) {
+				for _, path := range r.Prune(registered) {
+					fmt.Printf("Released resources of %s\n", path)
+				}
+			})
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/setup"
+)
+
+var (
+	relinkAbsolute bool
+)
+
+var relinkCmd = &cobra.Command{
+	Use:   "relink [worktree...]",
+	Short: "Convert setup symlinks to relative links",
+	Long: `Rewrite the symlinks that setup created from setup.link so that their
+targets are relative to the worktree instead of absolute paths. Relative
+links keep working when the whole workspace is moved, mounted in a
+container or synced to another machine.
+
+Without arguments all worktrees are converted. Only links pointing at the
+same path in the setup source are touched. Use --absolute to
+convert them back. Set setup.relative-links in .wt.json to create
+relative links for new worktrees.`,
+	ValidArgsFunction: completeLinkedWorktrees,
+	RunE:              runRelink,
+}
+
+func init() {
+	relinkCmd.Flags().BoolVar(&relinkAbsolute, "absolute", false, "Convert to absolute links instead")
+	rootCmd.AddCommand(relinkCmd)
+}
+
+func runRelink(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager := git.NewManager(repo)
+	source, err := setupSource(repo, manager, cfg)
+	if err != nil {
+		return err
+	}
+
+	var worktrees []git.Worktree
+	if len(args) == 0 {
+		all, err := manager.List()
+		if err != nil {
+			return err
+		}
+		for _, wt := range all {
+			if wt.Path != source && !wt.IsBare && !wt.IsPrunable {
+				worktrees = append(worktrees, wt)
+			}
+		}
+	}
+	for _, arg := range args {
+		wt, err := findWorktree(manager, arg)
+		if err != nil {
+			return err
+		}
+		if wt.Path == source {
+			return fmt.Errorf("%s is the setup source and has no setup links", wt.Path)
+		}
+		worktrees = append(worktrees, *wt)
+	}
+
+	patterns := config.Patterns(cfg.Setup.LinkEntries())
+	total := 0
+	for _, wt := range worktrees {
+		n, err := setup.ConvertLinks(source, wt.Path, patterns, !relinkAbsolute, true)
+		if err != nil {
+			return fmt.Errorf("%s: %w", wt.Path, err)
+		}
+		if n > 0 {
+			fmt.Printf("Relinked %d link(s) in %s\n", n, wt.Path)
+		}
+		total += n
+	}
+
+	if total == 0 {
+		fmt.Println("No links to convert.")
+	}
+	return nil
+}
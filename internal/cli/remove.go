@@ -7,11 +7,15 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/superkoh/worktree-manager/internal/config"
 	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/hooks"
+	"github.com/superkoh/worktree-manager/internal/resources"
+	"github.com/superkoh/worktree-manager/internal/shell"
 	"github.com/superkoh/worktree-manager/internal/tui"
+	"github.com/superkoh/worktree-manager/internal/util"
 )
 
 var (
-	removeForce        bool
+	removeForce        int
 	removeDeleteBranch bool
 )
 
@@ -21,26 +25,33 @@ var removeCmd = &cobra.Command{
 	Short:   "Remove a worktree",
 	Long: `Remove one or more worktrees.
 
-If no worktree is specified, an interactive selector will be shown.
-Use -f to force removal even if there are uncommitted changes.
-Use -D to also delete the associated branch.`,
-	RunE: runRemove,
+If no worktree is specified, an interactive selector will be shown where
+several worktrees can be checked with space (a to check all) and removed
+after confirmation.
+Use -f to force removal even if there are uncommitted changes,
+and -f -f to also remove locked worktrees.
+Use -D to also delete the associated branch.
+
+A worktree that cannot be removed, or whose pre-remove hook aborts, is
+skipped with a warning and the others are still removed.`,
+	ValidArgsFunction: completeLinkedWorktrees,
+	RunE:              runRemove,
 }
 
 func init() {
-	removeCmd.Flags().BoolVarP(&removeForce, "force", "f", false, "Force removal")
+	removeCmd.Flags().CountVarP(&removeForce, "force", "f", "Force removal (twice to remove locked worktrees)")
 	removeCmd.Flags().BoolVarP(&removeDeleteBranch, "delete-branch", "D", false, "Also delete the branch")
 	rootCmd.AddCommand(removeCmd)
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
-	repo, err := git.DetectRepository()
+	repo, err := git.DetectRepository(cmd.Context())
 	if err != nil {
 		return err
 	}
 
 	// Load configuration
-	cfg, err := config.Load(repo.RootPath)
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -51,7 +62,7 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 	if len(args) > 0 {
 		// Treat args as branch names and apply naming pattern
-		basedir, err := cfg.GetWorktreeBasedir(repo.RootPath)
+		basedir, err := cfg.GetWorktreeBasedir(repo.MainPath)
 		if err != nil {
 			return fmt.Errorf("failed to get basedir: %w", err)
 		}
@@ -59,16 +70,28 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		for _, branch := range args {
 			worktreeName := cfg.GenerateWorktreeName(repo.Name, branch)
 			worktreePath := filepath.Join(basedir, worktreeName)
+
+			// Fall back to a path or branch lookup for moved worktrees
+			if _, err := manager.FindByPath(worktreePath); err != nil {
+				if wt, err := findWorktree(manager, branch); err == nil {
+					worktreePath = wt.Path
+				}
+			}
 			paths = append(paths, worktreePath)
 		}
 	} else {
-		// Show TUI to select worktree
+		// Show TUI to select worktrees
 		worktrees, err := manager.List()
 		if err != nil {
 			return err
 		}
 
 		// Filter out main worktree and current worktree
+		if len(worktrees) > 0 {
+			worktrees = worktrees[1:]
+		}
+		manager.LoadStatus(worktrees)
+
 		var items []tui.Item
 		for _, wt := range worktrees {
 			if wt.IsCurrent {
@@ -77,7 +100,8 @@ func runRemove(cmd *cobra.Command, args []string) error {
 			items = append(items, tui.Item{
 				Name:        wt.Branch,
 				Path:        wt.Path,
-				Description: wt.Path,
+				Description: worktreeDescription(wt),
+				IsDirty:     wt.Status != nil && wt.Status.IsDirty(),
 			})
 		}
 
@@ -86,39 +110,105 @@ func runRemove(cmd *cobra.Command, args []string) error {
 			return nil
 		}
 
-		selected, err := tui.SelectWorktree(items)
+		prompt := "Remove these worktrees?"
+		if removeDeleteBranch {
+			prompt = "Remove these worktrees and delete their branches?"
+		}
+		if removeForce == 0 {
+			prompt += " (worktrees with uncommitted changes need -f)"
+		}
+
+		selected, err := tui.SelectWorktrees(items, prompt)
 		if err != nil {
 			return err
 		}
-		if selected == nil {
+		if len(selected) == 0 {
 			return fmt.Errorf("no worktree selected")
 		}
-		paths = []string{selected.Path}
+		for _, item := range selected {
+			paths = append(paths, item.Path)
+		}
+	}
+
+	// Set when the worktree the shell is in gets removed
+	returnTo := ""
+	// A worktree that cannot be removed is skipped so the rest still are
+	failed := 0
+	skip := func(err error) {
+		fmt.Printf("Warning: %v, skipping\n", err)
+		failed++
 	}
 
 	for _, path := range paths {
 		wt, err := manager.FindByPath(path)
 		if err != nil {
-			fmt.Printf("Warning: %v\n", err)
+			skip(err)
 			continue
 		}
 
+		if wt.IsLocked && removeForce < 2 {
+			skip(util.WorktreeLockedError(wt.Path, wt.LockReason))
+			continue
+		}
+		// Check before the pre-remove hooks, which shouldn't run for nothing
+		if removeForce == 0 {
+			if status, err := git.GetStatus(cmd.Context(), wt.Path); err == nil && status.IsDirty() {
+				skip(fmt.Errorf("worktree at '%s' has uncommitted changes, use -f to remove it anyway", wt.Path))
+				continue
+			}
+		}
+
 		branch := wt.Branch
+		hookCtx := newHookContext(repo, manager, wt.Path, branch)
+		if err := hooks.Run(cfg, hooks.PreRemove, hookCtx, false); err != nil {
+			skip(err)
+			continue
+		}
+
 		fmt.Printf("Removing worktree: %s (%s)\n", path, branch)
 
 		if err := manager.Remove(path, removeForce); err != nil {
-			return err
+			skip(err)
+			continue
 		}
 
+		// Continue from the main worktree once the current one is gone
+		if wt.IsCurrent && hookCtx.MainWorktree != "" {
+			returnTo = hookCtx.MainWorktree
+			if repo, err = git.DetectRepositoryFrom(cmd.Context(), returnTo); err != nil {
+				return err
+			}
+			manager = git.NewManager(repo)
+		}
+		updateResources(repo, func(r *resources.Registry) { r.Release(wt.Path) })
+
 		if removeDeleteBranch && branch != "" && branch != "(detached)" {
 			fmt.Printf("Deleting branch: %s\n", branch)
-			if err := manager.DeleteBranch(branch, removeForce); err != nil {
+			if err := manager.DeleteBranch(branch, removeForce > 0); err != nil {
 				fmt.Printf("Warning: failed to delete branch %s: %v\n", branch, err)
 			}
 		}
 
+		if err := hooks.Run(cfg, hooks.PostRemove, hookCtx, false); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
 		fmt.Println("Done!")
 	}
 
+	if returnTo != "" {
+		changed, err := shell.RequestCd(returnTo)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nRemoved the current worktree, returning to the main worktree.\n")
+		if !changed {
+			fmt.Printf("  cd %s\n", returnTo)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d worktree(s) could not be removed", failed, len(paths))
+	}
 	return nil
 }
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+// findWorktree resolves a worktree given as a path or a branch name
+func findWorktree(manager *git.Manager, arg string) (*git.Worktree, error) {
+	if wt, err := manager.FindByPath(arg); err == nil {
+		return wt, nil
+	}
+
+	wt, err := manager.FindByBranch(arg)
+	if err != nil {
+		return nil, err
+	}
+	if wt == nil {
+		return nil, util.WorktreeNotFoundError(arg)
+	}
+	return wt, nil
+}
+
+// worktreeDescription returns the TUI description of a worktree
+func worktreeDescription(wt git.Worktree) string {
+	if !wt.IsLocked {
+		return wt.Path
+	}
+	if wt.LockReason != "" {
+		return wt.Path + ", locked: " + wt.LockReason
+	}
+	return wt.Path + ", locked"
+}
+
+// BackendEnv selects the git backend of read-only commands; set it to
+// "exec" to run git instead of reading the git directory
+const BackendEnv = "WT_GIT_BACKEND"
+
+// detectRepositoryForReading detects the repository for commands that only
+// read worktrees and refs, such as list, select and completion. They use
+// the native backend unless BackendEnv asks for git.
+func detectRepositoryForReading(ctx context.Context) (*git.Repository, error) {
+	repo, err := git.DetectRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv(BackendEnv) != "exec" {
+		repo.UseNativeBackend()
+	}
+	return repo, nil
+}
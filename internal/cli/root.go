@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"context"
 	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 )
@@ -16,9 +18,22 @@ automatic file copying/linking based on .wt.json configuration.`,
 	SilenceUsage: true,
 }
 
-// Execute runs the root command
+// Execute runs the root command. Ctrl-C cancels the context of the command,
+// which stops the git commands it runs; a second Ctrl-C ends wt right away.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+	if interrupted {
+		os.Exit(130)
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
 	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/hooks"
+	"github.com/superkoh/worktree-manager/internal/shell"
 	"github.com/superkoh/worktree-manager/internal/tui"
 )
 
@@ -13,13 +18,18 @@ var (
 )
 
 var selectCmd = &cobra.Command{
-	Use:   "select",
+	Use:   "select [worktree]",
 	Short: "Interactively select a worktree",
 	Long: `Open an interactive TUI to select an existing worktree.
 
-This is useful for quickly switching between worktrees.
+This is useful for quickly switching between worktrees. The selector
+shows the changes, upstream state and recent commits of the highlighted
+worktree; press tab to hide or show the preview. A worktree can
+also be given as a path or branch name to skip the selector.
 Use --print-path to print the selected path (for shell integration).`,
-	RunE: runSelect,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeOneWorktree,
+	RunE:              runSelect,
 }
 
 func init() {
@@ -28,11 +38,17 @@ func init() {
 }
 
 func runSelect(cmd *cobra.Command, args []string) error {
-	repo, err := git.DetectRepository()
+	repo, err := detectRepositoryForReading(cmd.Context())
 	if err != nil {
 		return err
 	}
 
+	// Load configuration
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
 	manager := git.NewManager(repo)
 	worktrees, err := manager.List()
 	if err != nil {
@@ -45,7 +61,10 @@ func runSelect(cmd *cobra.Command, args []string) error {
 	}
 
 	var items []tui.Item
+	branches := make(map[string]string, len(worktrees))
 	for _, wt := range worktrees {
+		branches[wt.Path] = wt.Branch
+
 		status := ""
 		if wt.IsCurrent {
 			status = " (current)"
@@ -53,25 +72,92 @@ func runSelect(cmd *cobra.Command, args []string) error {
 		items = append(items, tui.Item{
 			Name:        wt.Branch + status,
 			Path:        wt.Path,
-			Description: wt.Path,
+			Description: worktreeDescription(wt),
 			IsCurrent:   wt.IsCurrent,
 		})
 	}
 
-	selected, err := tui.SelectWorktree(items)
-	if err != nil {
+	var selected *tui.Item
+	if len(args) > 0 {
+		wt, err := findWorktree(manager, args[0])
+		if err != nil {
+			return err
+		}
+		selected = &tui.Item{Name: wt.Branch, Path: wt.Path}
+	} else {
+		selected, err = tui.SelectWorktreeWithPreview(cmd.Context(), items, previewWorktree)
+		if err != nil {
+			return err
+		}
+		if selected == nil {
+			return nil
+		}
+	}
+
+	hookCtx := newHookContext(repo, manager, selected.Path, branches[selected.Path])
+	if err := hooks.Run(cfg, hooks.PostSelect, hookCtx, selectPrintPath); err != nil {
 		return err
 	}
-	if selected == nil {
-		return nil
+
+	changed, err := shell.RequestCd(selected.Path)
+	if err != nil {
+		return err
 	}
 
 	if selectPrintPath {
 		fmt.Println(selected.Path)
 	} else {
 		fmt.Printf("Selected: %s\n", selected.Path)
-		fmt.Printf("  cd %s\n", selected.Path)
+		if !changed {
+			fmt.Printf("  cd %s\n", selected.Path)
+		}
 	}
 
 	return nil
 }
+
+// previewCommits is the number of commits shown in the selector preview
+const previewCommits = 5
+
+// previewWorktree renders the selector preview of a worktree: its upstream
+// state, uncommitted changes and most recent commits
+func previewWorktree(ctx context.Context, item tui.Item) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, item.Path)
+
+	status, err := git.GetStatus(ctx, item.Path)
+	if err != nil {
+		fmt.Fprintf(&b, "\n%v\n", err)
+		return b.String()
+	}
+
+	if status.Upstream != "" {
+		fmt.Fprintf(&b, "Upstream: %s (%s)\n", status.Upstream, formatSync(status))
+	} else {
+		fmt.Fprintln(&b, "Upstream: none")
+	}
+
+	changes, err := git.ShortStatus(ctx, item.Path)
+	if err != nil {
+		fmt.Fprintf(&b, "\n%v\n", err)
+		return b.String()
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(&b, "\nNo uncommitted changes")
+	} else {
+		fmt.Fprintf(&b, "\nChanges (%s):\n", formatChanges(status))
+		for _, line := range changes {
+			fmt.Fprintln(&b, line)
+		}
+	}
+
+	commits, _ := git.RecentCommits(ctx, item.Path, previewCommits)
+	if len(commits) > 0 {
+		fmt.Fprintln(&b, "\nRecent commits:")
+		for _, line := range commits {
+			fmt.Fprintln(&b, line)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/shell"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <shell>",
+	Short: "Print the shell integration script",
+	Long: `Print a wrapper function that lets wt change the current directory of
+your shell, e.g. after wt add, wt select or removing the current worktree.
+
+Supported shells: bash, zsh, fish, powershell, nushell.
+
+  bash/zsh:    eval "$(wt shell-init bash)"
+  fish:        wt shell-init fish | source
+  powershell:  Invoke-Expression (& wt shell-init powershell | Out-String)
+  nushell:     wt shell-init nushell | save -f ~/.config/nushell/wt.nu
+               then add 'source ~/.config/nushell/wt.nu' to config.nu
+
+The wrapper passes a temporary file to wt through WT_CD_FILE; wt writes the
+target directory there instead of printing it, so normal output and
+warnings are never mistaken for a path.`,
+	ValidArgs: shell.Names(),
+	Args:      cobra.ExactArgs(1),
+	RunE:      runShellInit,
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+}
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	script, err := shell.Script(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
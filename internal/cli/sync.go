@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+	"github.com/superkoh/worktree-manager/internal/resources"
+	"github.com/superkoh/worktree-manager/internal/setup"
+)
+
+var (
+	syncAll    bool
+	syncYes    bool
+	syncForce  bool
+	syncDryRun bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [worktree...]",
+	Short: "Re-apply setup to existing worktrees",
+	Long: `Compare existing worktrees with the setup in .wt.json and bring them
+up to date with the setup source, the main worktree unless setup.source
+names another.
+
+Copies and rendered templates that are missing, or whose source changed
+while the worktree's file did not, are updated. Missing links and links
+pointing elsewhere are recreated. Files changed in the worktree since setup
+are left alone unless --force is given, and paths tracked by git are
+never replaced unless their entry sets allow-tracked.
+
+Without arguments the current worktree is synced; use --all for every
+linked worktree. The plan is shown and confirmed before anything changes.`,
+	ValidArgsFunction: completeLinkedWorktrees,
+	RunE:              runSync,
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncAll, "all", false, "Sync all linked worktrees")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Do not ask for confirmation")
+	syncCmd.Flags().BoolVarP(&syncForce, "force", "f", false, "Overwrite files changed in the worktree")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show the plan without changing anything")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// syncTarget is a worktree and the setup entries that differ in it
+type syncTarget struct {
+	worktree git.Worktree
+	items    []setup.SyncItem
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	repo, err := git.DetectRepository(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load(repo.RootPath, repo.MainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager := git.NewManager(repo)
+	source, err := setupSource(repo, manager, cfg)
+	if err != nil {
+		return err
+	}
+
+	worktrees, err := syncWorktrees(manager, source, args)
+	if err != nil {
+		return err
+	}
+
+	// Ports declared since a worktree was created are allocated on apply
+	commonDir, err := repo.GetCommonDir()
+	if err != nil {
+		return err
+	}
+	registry, err := resources.Load(commonDir)
+	if err != nil {
+		return err
+	}
+
+	var targets []syncTarget
+	for _, wt := range worktrees {
+		ports, err := registry.AllocatePorts(wt.Path, cfg.Resources.Ports)
+		if err != nil {
+			return err
+		}
+		hookCtx := newHookContext(repo, manager, wt.Path, wt.Branch)
+		hookCtx.Ports = ports
+
+		items, err := setup.PlanSync(cmd.Context(), cfg, source, wt.Path, newTemplateData(repo, hookCtx))
+		if err != nil {
+			return fmt.Errorf("%s: %w", wt.Path, err)
+		}
+		if len(items) > 0 {
+			targets = append(targets, syncTarget{worktree: wt, items: items})
+		}
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("Everything is up to date.")
+		return nil
+	}
+
+	pending, blocked := printSyncPlan(targets)
+	if blocked > 0 && !syncForce {
+		fmt.Printf("\n%d path(s) changed in the worktree are skipped, use --force to overwrite them.\n", blocked)
+	}
+
+	if syncDryRun || pending == 0 {
+		return nil
+	}
+
+	if !syncYes && !confirm(fmt.Sprintf("\nApply %d change(s)?", pending)) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	if len(cfg.Resources.Ports) > 0 {
+		// Allocated again under the registry lock, as the plan was made without it
+		err := resources.Update(commonDir, func(r *resources.Registry) error {
+			for _, wt := range worktrees {
+				ports, err := r.AllocatePorts(wt.Path, cfg.Resources.Ports)
+				if err != nil {
+					return err
+				}
+				if !maps.Equal(ports, registry.Ports[wt.Path]) {
+					return fmt.Errorf("ports planned for %s were taken by another wt process, run wt sync again", wt.Path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save resource registry: %w", err)
+		}
+	}
+
+	applied := 0
+	for _, t := range targets {
+		if !syncForce && !hasUnblocked(t.items) {
+			continue
+		}
+		fmt.Printf("\nSyncing %s (%s)\n", t.worktree.Path, t.worktree.Branch)
+		n, err := setup.ApplySync(cmd.Context(), source, t.worktree.Path, t.items, syncForce, false)
+		applied += n
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\nApplied %d change(s).\n", applied)
+	return nil
+}
+
+// syncWorktrees returns the worktrees named in args, all worktrees but the
+// setup source with --all, or the current worktree
+func syncWorktrees(manager *git.Manager, source string, args []string) ([]git.Worktree, error) {
+	if syncAll {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("--all cannot be combined with worktree arguments")
+		}
+		worktrees, err := manager.List()
+		if err != nil {
+			return nil, err
+		}
+		var targets []git.Worktree
+		for _, wt := range worktrees {
+			if wt.Path != source && !wt.IsBare && !wt.IsPrunable {
+				targets = append(targets, wt)
+			}
+		}
+		return targets, nil
+	}
+
+	if len(args) == 0 {
+		worktrees, err := manager.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, wt := range worktrees {
+			if wt.IsCurrent && wt.Path != source {
+				return []git.Worktree{wt}, nil
+			}
+		}
+		return nil, fmt.Errorf("the current worktree is the setup source, name worktrees to sync or use --all")
+	}
+
+	var result []git.Worktree
+	for _, arg := range args {
+		wt, err := findWorktree(manager, arg)
+		if err != nil {
+			return nil, err
+		}
+		if wt.Path == source {
+			return nil, fmt.Errorf("%s is the setup source and cannot be synced", wt.Path)
+		}
+		result = append(result, *wt)
+	}
+	return result, nil
+}
+
+// hasUnblocked reports whether any item can be applied without --force
+func hasUnblocked(items []setup.SyncItem) bool {
+	for _, item := range items {
+		if !item.Blocked && !item.Protected {
+			return true
+		}
+	}
+	return false
+}
+
+// printSyncPlan prints the sync plan as a table and returns the number of
+// changes to apply and of changes skipped without --force
+func printSyncPlan(targets []syncTarget) (pending, blocked int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tKIND\tPATH\tSTATE\tACTION")
+	fmt.Fprintln(w, "------\t----\t----\t-----\t------")
+
+	for _, t := range targets {
+		for _, item := range t.items {
+			action := "update"
+			switch {
+			case item.Protected:
+				action = "keep (tracked by git)"
+			case item.Blocked && !syncForce:
+				action = "skip (changed in worktree)"
+				blocked++
+			case item.Blocked:
+				action = "overwrite"
+				pending++
+			default:
+				pending++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.worktree.Branch, item.Kind, item.Path, item.State, action)
+		}
+	}
+
+	w.Flush()
+	return pending, blocked
+}
@@ -2,18 +2,29 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/superkoh/worktree-manager/internal/util"
 )
 
-const ConfigFileName = ".wt.json"
+const (
+	// ConfigFileName is the repository configuration file
+	ConfigFileName = ".wt.json"
+	// LocalConfigFileName holds personal, untracked overrides
+	LocalConfigFileName = ".wt.local.json"
+)
 
 // Config represents the .wt.json configuration file
 type Config struct {
-	Version  string          `json:"version"`
-	Worktree WorktreeConfig  `json:"worktree"`
-	Setup    SetupConfig     `json:"setup"`
+	Version   string          `json:"version"`
+	Worktree  WorktreeConfig  `json:"worktree"`
+	Setup     SetupConfig     `json:"setup"`
+	Hooks     HooksConfig     `json:"hooks"`
+	Clean     CleanConfig     `json:"clean"`
+	Resources ResourcesConfig `json:"resources"`
 }
 
 // WorktreeConfig defines worktree creation settings
@@ -23,12 +34,184 @@ type WorktreeConfig struct {
 	Sanitize map[string]string `json:"sanitize"`
 }
 
-// SetupConfig defines files to copy or link
+// SetupConfig defines files to copy, link or render
 type SetupConfig struct {
-	Copy []string `json:"copy"`
-	Link []string `json:"link"`
+	Copy []SetupEntry `json:"copy"`
+	Link []SetupEntry `json:"link"`
+	// Template lists files rendered with text/template into new worktrees
+	Template []string `json:"template,omitempty"`
+	// RelativeLinks creates symlinks with targets relative to the worktree
+	RelativeLinks bool `json:"relative-links,omitempty"`
+	// Source is the worktree files are copied and linked from: main,
+	// current, or a worktree named by branch or path
+	Source string `json:"source,omitempty"`
+}
+
+// Setup sources other than a named worktree
+const (
+	SourceMain    = "main"
+	SourceCurrent = "current"
+)
+
+// SetupEntry is a path or glob pattern in setup.copy or setup.link.
+// It is written as a plain string, or as an object to set options.
+type SetupEntry struct {
+	Path string `json:"path"`
+	// Policy decides what happens when the destination already exists
+	Policy string `json:"policy,omitempty"`
+	// AllowTracked permits replacing paths tracked by git in the worktree
+	AllowTracked bool `json:"allow-tracked,omitempty"`
+	// Mode decides how the entry is created, overriding the list it is in
+	Mode string `json:"mode,omitempty"`
+}
+
+// Setup entry policies for existing destinations
+const (
+	PolicyOverwrite = "overwrite"
+	PolicySkip      = "skip"
+	PolicyBackup    = "backup"
+	PolicyFail      = "fail"
+	PolicyMergeEnv  = "merge-env"
+)
+
+// Setup entry modes. Entries in setup.copy default to copy, entries in
+// setup.link to symlink. Reflink clones files on copy-on-write file systems
+// and falls back to copying elsewhere; hardlink falls back to copying
+// across file systems.
+const (
+	ModeCopy     = "copy"
+	ModeSymlink  = "symlink"
+	ModeHardlink = "hardlink"
+	ModeReflink  = "reflink"
+)
+
+// UnmarshalJSON accepts a plain path as well as an entry object
+func (e *SetupEntry) UnmarshalJSON(data []byte) error {
+	var p string
+	if err := json.Unmarshal(data, &p); err == nil {
+		*e = SetupEntry{Path: p}
+		return nil
+	}
+
+	type entry SetupEntry
+	var v entry
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("setup entry must be a path or an object: %w", err)
+	}
+	*e = SetupEntry(v)
+	return nil
+}
+
+// MarshalJSON writes entries without options as plain paths
+func (e SetupEntry) MarshalJSON() ([]byte, error) {
+	if e == (SetupEntry{Path: e.Path}) {
+		return json.Marshal(e.Path)
+	}
+	type entry SetupEntry
+	return json.Marshal(entry(e))
+}
+
+// EffectivePolicy returns the entry policy, defaulting to overwrite
+func (e SetupEntry) EffectivePolicy() string {
+	if e.Policy == "" {
+		return PolicyOverwrite
+	}
+	return e.Policy
+}
+
+// CopyEntries returns the entries created as files: those in setup.copy and
+// those in setup.link with another mode than symlink. Their Mode is set.
+func (s SetupConfig) CopyEntries() []SetupEntry {
+	var entries []SetupEntry
+	for _, e := range s.Copy {
+		if e.Mode == "" {
+			e.Mode = ModeCopy
+		}
+		if e.Mode != ModeSymlink {
+			entries = append(entries, e)
+		}
+	}
+	for _, e := range s.Link {
+		if e.Mode != "" && e.Mode != ModeSymlink {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// LinkEntries returns the entries created as symlinks: those in setup.link
+// and those in setup.copy with the symlink mode. Their Mode is set.
+func (s SetupConfig) LinkEntries() []SetupEntry {
+	var entries []SetupEntry
+	for _, e := range s.Copy {
+		if e.Mode == ModeSymlink {
+			entries = append(entries, e)
+		}
+	}
+	for _, e := range s.Link {
+		if e.Mode == "" || e.Mode == ModeSymlink {
+			e.Mode = ModeSymlink
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Entries returns setup entries for plain paths
+func Entries(paths ...string) []SetupEntry {
+	entries := make([]SetupEntry, len(paths))
+	for i, p := range paths {
+		entries[i] = SetupEntry{Path: p}
+	}
+	return entries
+}
+
+// Patterns returns the paths of setup entries
+func Patterns(entries []SetupEntry) []string {
+	patterns := make([]string, len(entries))
+	for i, e := range entries {
+		patterns[i] = e.Path
+	}
+	return patterns
+}
+
+// HooksConfig defines shell commands run at worktree lifecycle points
+type HooksConfig struct {
+	PostCreate []string `json:"post-create,omitempty"`
+	PreRemove  []string `json:"pre-remove,omitempty"`
+	PostRemove []string `json:"post-remove,omitempty"`
+	PostSelect []string `json:"post-select,omitempty"`
+	// OnFailure is either "warn" (default) or "abort"
+	OnFailure string `json:"on-failure,omitempty"`
+}
+
+// CleanConfig defines which worktrees wt clean considers finished
+type CleanConfig struct {
+	// Base is the branch merged worktrees are compared against.
+	// Defaults to the remote default branch or the main worktree branch.
+	Base string `json:"base,omitempty"`
+	// StaleDays marks worktrees without commits for this many days; 0 disables it
+	StaleDays int `json:"stale-days,omitempty"`
+}
+
+// ResourcesConfig declares resources allocated to each worktree
+type ResourcesConfig struct {
+	// Ports maps a port name such as "web" to the range it is allocated from
+	Ports map[string]PortRange `json:"ports,omitempty"`
+}
+
+// PortRange is an inclusive range of TCP ports
+type PortRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
 }
 
+// Hook failure policies
+const (
+	HookFailureWarn  = "warn"
+	HookFailureAbort = "abort"
+)
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -39,43 +222,107 @@ func DefaultConfig() *Config {
 			Sanitize: map[string]string{"/": "-", ":": "-"},
 		},
 		Setup: SetupConfig{
-			Copy: []string{},
-			Link: []string{},
+			Copy: []SetupEntry{},
+			Link: []SetupEntry{},
 		},
 	}
 }
 
-// Load loads configuration from the given directory
-// It searches upward to find .wt.json in a git repository
-func Load(startDir string) (*Config, error) {
-	configPath, err := FindConfigFile(startDir)
-	if err != nil {
-		// Return default config if not found
-		return DefaultConfig(), nil
-	}
+// Load loads the effective configuration for the given directory.
+// The user config, the repository .wt.json (searched upward from startDir)
+// and the untracked .wt.local.json, from startDir or else the main worktree
+// mainDir, are merged field by field, in that order.
+func Load(startDir, mainDir string) (*Config, error) {
+	cfg, _, err := LoadWithOrigins(startDir, mainDir)
+	return cfg, err
+}
 
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
+// Validate checks the configuration for invalid values
+func (c *Config) Validate() error {
+	switch c.Hooks.OnFailure {
+	case "", HookFailureWarn, HookFailureAbort:
+	default:
+		return util.ConfigInvalidError(fmt.Sprintf("hooks.on-failure must be %q or %q, got %q",
+			HookFailureWarn, HookFailureAbort, c.Hooks.OnFailure))
 	}
+	if c.Clean.StaleDays < 0 {
+		return util.ConfigInvalidError("clean.stale-days must not be negative")
+	}
+	for _, e := range c.Setup.Copy {
+		if err := validateEntry("setup.copy", e, ModeCopy); err != nil {
+			return err
+		}
+	}
+	for _, e := range c.Setup.Link {
+		if err := validateEntry("setup.link", e, ModeSymlink); err != nil {
+			return err
+		}
+	}
+	for name, r := range c.Resources.Ports {
+		if !isResourceName(name) {
+			return util.ConfigInvalidError(fmt.Sprintf("resources.ports: invalid name %q (use letters, digits, - and _)", name))
+		}
+		if r.Min < 1 || r.Max > 65535 || r.Min > r.Max {
+			return util.ConfigInvalidError(fmt.Sprintf("resources.ports.%s: invalid range %d-%d", name, r.Min, r.Max))
+		}
+	}
+	return nil
+}
 
-	cfg := DefaultConfig()
-	if err := json.Unmarshal(data, cfg); err != nil {
-		return nil, err
+// validateEntry checks the options of a setup entry in a list whose
+// entries are created with defaultMode
+func validateEntry(field string, e SetupEntry, defaultMode string) error {
+	if strings.TrimSpace(e.Path) == "" {
+		return util.ConfigInvalidError(field + ": entry without path")
 	}
+	mode := e.Mode
+	switch mode {
+	case "":
+		mode = defaultMode
+	case ModeCopy, ModeSymlink, ModeHardlink, ModeReflink:
+	default:
+		return util.ConfigInvalidError(fmt.Sprintf("%s: %s: unknown mode %q", field, e.Path, e.Mode))
+	}
+	switch e.Policy {
+	case "", PolicyOverwrite, PolicySkip, PolicyBackup, PolicyFail:
+	case PolicyMergeEnv:
+		// Merging into a hardlink would change the source as well
+		if mode != ModeCopy && mode != ModeReflink {
+			return util.ConfigInvalidError(fmt.Sprintf("%s: %s: policy %q only applies to copy and reflink modes", field, e.Path, e.Policy))
+		}
+	default:
+		return util.ConfigInvalidError(fmt.Sprintf("%s: %s: unknown policy %q", field, e.Path, e.Policy))
+	}
+	return nil
+}
 
-	return cfg, nil
+// isResourceName reports whether name can be used in environment variables
+func isResourceName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
 }
 
 // FindConfigFile searches for .wt.json starting from dir and going up
 func FindConfigFile(dir string) (string, error) {
+	return findUpward(dir, ConfigFileName)
+}
+
+// findUpward searches for a file named name starting from dir and going up
+func findUpward(dir, name string) (string, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", err
 	}
 
 	for {
-		configPath := filepath.Join(absDir, ConfigFileName)
+		configPath := filepath.Join(absDir, name)
 		if _, err := os.Stat(configPath); err == nil {
 			return configPath, nil
 		}
@@ -114,8 +361,9 @@ func (c *Config) GenerateWorktreeName(repoName, branch string) string {
 
 // GetWorktreeBasedir returns the absolute base directory for worktrees
 func (c *Config) GetWorktreeBasedir(repoRoot string) (string, error) {
-	if filepath.IsAbs(c.Worktree.Basedir) {
-		return c.Worktree.Basedir, nil
+	basedir := util.ExpandHome(c.Worktree.Basedir)
+	if filepath.IsAbs(basedir) {
+		return basedir, nil
 	}
-	return filepath.Abs(filepath.Join(repoRoot, c.Worktree.Basedir))
+	return filepath.Abs(filepath.Join(repoRoot, basedir))
 }
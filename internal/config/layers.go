@@ -0,0 +1,182 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+// Layer is a configuration source
+type Layer struct {
+	Name string
+	Path string
+}
+
+func (l Layer) String() string {
+	return fmt.Sprintf("%s (%s)", l.Name, l.Path)
+}
+
+// UserConfigPath returns the path of the user-level configuration file:
+// $XDG_CONFIG_HOME/wt/config.json, falling back to ~/.config on Unix and
+// %AppData% on Windows
+func UserConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir, _ = os.UserConfigDir()
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config")
+		}
+	}
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "wt", "config.json")
+}
+
+// Layers returns the configuration sources for startDir, lowest priority first.
+// mainDir is the main worktree, searched for the local override when startDir
+// has none; it may be empty. Files that don't exist are omitted.
+func Layers(startDir, mainDir string) []Layer {
+	var layers []Layer
+
+	if p := UserConfigPath(); p != "" && util.FileExists(p) {
+		layers = append(layers, Layer{Name: "user", Path: p})
+	}
+
+	if repoPath, err := FindConfigFile(startDir); err == nil {
+		layers = append(layers, Layer{Name: "repo", Path: repoPath})
+	}
+
+	// Being untracked, the local override is often only in the main worktree
+	for _, dir := range []string{startDir, mainDir} {
+		if dir == "" {
+			continue
+		}
+		if local, err := findLocalConfigFile(dir); err == nil {
+			layers = append(layers, Layer{Name: "local", Path: local})
+			break
+		}
+	}
+
+	return layers
+}
+
+// findLocalConfigFile returns the .wt.local.json for dir. It lives next to
+// .wt.json, or is searched on its own when there is no .wt.json.
+func findLocalConfigFile(dir string) (string, error) {
+	repoPath, err := FindConfigFile(dir)
+	if err != nil {
+		return findUpward(dir, LocalConfigFileName)
+	}
+	local := filepath.Join(filepath.Dir(repoPath), LocalConfigFileName)
+	if !util.FileExists(local) {
+		return "", os.ErrNotExist
+	}
+	return local, nil
+}
+
+// LoadWithOrigins loads the effective configuration like Load and also
+// returns, for each dotted key set by a file, the layer it came from
+func LoadWithOrigins(startDir, mainDir string) (*Config, map[string]Layer, error) {
+	merged := map[string]any{}
+	origins := map[string]Layer{}
+
+	for _, layer := range Layers(startDir, mainDir) {
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var values map[string]any
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", layer.Path, err)
+		}
+		mergeValues(merged, values, "", layer, origins)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, origins, nil
+}
+
+// mergeValues merges src into dst. Objects are merged key by key, while
+// arrays and scalars replace the previous value.
+func mergeValues(dst, src map[string]any, prefix string, layer Layer, origins map[string]Layer) {
+	for key, value := range src {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if obj, ok := value.(map[string]any); ok {
+			sub, ok := dst[key].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				dst[key] = sub
+			}
+			mergeValues(sub, obj, path, layer, origins)
+			continue
+		}
+
+		dst[key] = value
+		origins[path] = layer
+	}
+}
+
+// Flatten returns the configuration as sorted dotted keys and JSON values
+func (c *Config) Flatten() ([]string, map[string]string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, nil, err
+	}
+
+	flat := map[string]string{}
+	flattenValues(values, "", flat)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, flat, nil
+}
+
+func flattenValues(values map[string]any, prefix string, flat map[string]string) {
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if obj, ok := value.(map[string]any); ok {
+			flattenValues(obj, path, flat)
+			continue
+		}
+
+		data, _ := json.Marshal(value)
+		flat[path] = string(data)
+	}
+}
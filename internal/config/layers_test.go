@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile writes data to dir/name and fails the test on error
+func writeFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLocalConfigFromMainWorktree(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	base := t.TempDir()
+	main := filepath.Join(base, "repo")
+	linked := filepath.Join(base, "repo-feature")
+
+	// .wt.json is tracked and in every worktree, .wt.local.json only in main
+	writeFile(t, main, ConfigFileName, `{"worktree": {"naming": "{branch}"}}`)
+	writeFile(t, main, LocalConfigFileName, `{"clean": {"stale-days": 7}}`)
+	writeFile(t, linked, ConfigFileName, `{"worktree": {"naming": "{branch}"}}`)
+
+	cfg, origins, err := LoadWithOrigins(linked, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Clean.StaleDays != 7 {
+		t.Errorf("clean.stale-days = %d, want 7 from the main worktree", cfg.Clean.StaleDays)
+	}
+	if want := filepath.Join(main, LocalConfigFileName); origins["clean.stale-days"].Path != want {
+		t.Errorf("clean.stale-days came from %s, want %s", origins["clean.stale-days"], want)
+	}
+	if cfg.Worktree.Naming != "{branch}" {
+		t.Errorf("worktree.naming = %q, want {branch}", cfg.Worktree.Naming)
+	}
+
+	// A local file in the linked worktree takes precedence
+	writeFile(t, linked, LocalConfigFileName, `{"clean": {"stale-days": 3}}`)
+	cfg, err = Load(linked, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Clean.StaleDays != 3 {
+		t.Errorf("clean.stale-days = %d, want 3 from the linked worktree", cfg.Clean.StaleDays)
+	}
+}
+
+func TestLoadLocalConfigWithoutRepoConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	base := t.TempDir()
+	main := filepath.Join(base, "repo")
+	linked := filepath.Join(base, "repo-feature")
+
+	writeFile(t, main, LocalConfigFileName, `{"clean": {"stale-days": 7}}`)
+	if err := os.MkdirAll(linked, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(linked, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Clean.StaleDays != 7 {
+		t.Errorf("clean.stale-days = %d, want 7 from the main worktree", cfg.Clean.StaleDays)
+	}
+}
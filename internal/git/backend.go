@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"strings"
+)
+
+// Backend reads the state of a repository. The exec backend runs git for
+// everything; the native backend reads the git directory directly for
+// listing worktrees and refs, which keeps list, select and completion
+// fast on large repositories.
+type Backend interface {
+	// ListWorktrees returns the worktrees in the order of git worktree list
+	ListWorktrees() ([]Worktree, error)
+	// ListRemotes returns the configured remotes
+	ListRemotes() ([]string, error)
+	// ListRefs returns the full names of the refs under prefix, sorted
+	ListRefs(prefix string) ([]string, error)
+	// ResolveRef returns the commit a ref points to
+	ResolveRef(ref string) (string, error)
+	// Status returns the status of the worktree at path
+	Status(path string) (*WorktreeStatus, error)
+}
+
+// execBackend implements Backend by running git in dir
+type execBackend struct {
+	ctx context.Context
+	dir string
+}
+
+// NewExecBackend returns a backend running git in dir until ctx ends
+func NewExecBackend(ctx context.Context, dir string) Backend {
+	return &execBackend{ctx: ctx, dir: dir}
+}
+
+func (b *execBackend) ListWorktrees() ([]Worktree, error) {
+	output, err := runGit(b.ctx, b.dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return parseWorktreeList(string(output))
+}
+
+func (b *execBackend) ListRemotes() ([]string, error) {
+	output, err := runGit(b.ctx, b.dir, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(string(output)), nil
+}
+
+func (b *execBackend) ListRefs(prefix string) ([]string, error) {
+	output, err := runGit(b.ctx, b.dir, "for-each-ref", "--format=%(refname)", prefix)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(string(output)), nil
+}
+
+func (b *execBackend) ResolveRef(ref string) (string, error) {
+	output, err := runGit(b.ctx, b.dir, "rev-parse", "--verify", "--quiet", ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (b *execBackend) Status(path string) (*WorktreeStatus, error) {
+	return GetStatus(b.ctx, path)
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CloneHub clones url as a bare repository into the .bare directory of
+// hub and points hub/.git at it, so that git and wt work from the hub
+// directory. Origin is set up to fetch branches into remote-tracking refs,
+// which a bare clone doesn't do, and its HEAD is set to the default branch.
+// The returned repository keeps ctx for its git commands.
+func CloneHub(ctx context.Context, url, hub string, out io.Writer) (*Repository, error) {
+	if err := os.MkdirAll(hub, 0755); err != nil {
+		return nil, err
+	}
+
+	bareDir := filepath.Join(hub, BareDirName)
+	if err := runGitInteractive(ctx, "", out, "clone", "--bare", url, bareDir); err != nil {
+		return nil, err
+	}
+
+	gitFile := filepath.Join(hub, ".git")
+	if err := os.WriteFile(gitFile, []byte("gitdir: ./"+BareDirName+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", gitFile, err)
+	}
+
+	if _, err := runGit(ctx, hub, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return nil, err
+	}
+	if err := runGitInteractive(ctx, hub, out, "fetch", "origin"); err != nil {
+		return nil, err
+	}
+
+	repo, err := DetectRepositoryFrom(ctx, hub)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remember the default branch and let it track origin
+	if branch, err := repo.DefaultBranch(); err == nil && repo.BranchExists(branch) {
+		if _, err := repo.run("symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/"+branch); err != nil {
+			return nil, err
+		}
+		if _, err := repo.run("branch", "--set-upstream-to=origin/"+branch, branch); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
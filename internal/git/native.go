@@ -0,0 +1,394 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSymrefDepth limits how many symbolic refs are followed
+const maxSymrefDepth = 5
+
+// nativeExtensions are the repository extensions that don't change how the
+// native backend reads the git directory. Others, such as the reftable ref
+// storage, leave the repository to git.
+var nativeExtensions = map[string]bool{
+	"objectformat":    true,
+	"worktreeconfig":  true,
+	"partialclone":    true,
+	"preciousobjects": true,
+	"noop":            true,
+}
+
+// nativeBackend implements the listing operations of Backend by reading
+// the git directory, and falls back to running git for the rest and for
+// layouts it doesn't understand
+type nativeBackend struct {
+	gitDir    string
+	commonDir string
+	exec      *execBackend
+}
+
+// NewNativeBackend returns a backend reading the git directory of the
+// worktree at root. It fails if root has no git directory it can find, or
+// the repository uses an extension it can't read.
+// Commands run by the fallback end when ctx does.
+func NewNativeBackend(ctx context.Context, root string) (Backend, error) {
+	gitDir, err := WorktreeGitDir(root)
+	if err != nil {
+		// A bare repository is its own git directory
+		if !isGitDir(root) {
+			return nil, err
+		}
+		gitDir = root
+	}
+
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+
+	b := &nativeBackend{
+		gitDir:    gitDir,
+		commonDir: filepath.Clean(commonDir),
+		exec:      &execBackend{ctx: ctx, dir: root},
+	}
+	for key, value := range b.configSection("extensions") {
+		if key == "refstorage" && strings.EqualFold(value, "files") {
+			continue
+		}
+		if !nativeExtensions[key] {
+			return nil, fmt.Errorf("unsupported repository extension %s", key)
+		}
+	}
+	return b, nil
+}
+
+func (b *nativeBackend) ListWorktrees() ([]Worktree, error) {
+	var main Worktree
+	switch {
+	case b.configBool("core", "bare"):
+		main = Worktree{Path: b.commonDir, IsBare: true}
+	case filepath.Base(b.commonDir) == ".git":
+		main = Worktree{Path: filepath.Dir(b.commonDir)}
+		b.readHead(&main, b.commonDir)
+	default:
+		// The main worktree is set by core.worktree or the environment
+		return b.exec.ListWorktrees()
+	}
+
+	adminDir := filepath.Join(b.commonDir, "worktrees")
+	entries, err := os.ReadDir(adminDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var linked []Worktree
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(adminDir, entry.Name())
+		gitFile, err := readTrimmed(filepath.Join(dir, "gitdir"))
+		if err != nil {
+			return b.exec.ListWorktrees()
+		}
+		// Relative with worktree.useRelativePaths
+		if !filepath.IsAbs(gitFile) {
+			gitFile = filepath.Join(dir, gitFile)
+		}
+		gitFile = filepath.Clean(gitFile)
+
+		wt := Worktree{Path: filepath.Dir(gitFile)}
+		b.readHead(&wt, dir)
+		if reason, err := readTrimmed(filepath.Join(dir, "locked")); err == nil {
+			wt.IsLocked = true
+			wt.LockReason = reason
+		} else if _, err := os.Stat(gitFile); err != nil {
+			wt.IsPrunable = true
+		}
+		linked = append(linked, wt)
+	}
+
+	// Like git, keep the main worktree first and sort the others by path
+	sort.Slice(linked, func(i, j int) bool { return linked[i].Path < linked[j].Path })
+	return append([]Worktree{main}, linked...), nil
+}
+
+// readHead fills the branch and commit of wt from the HEAD in gitDir
+func (b *nativeBackend) readHead(wt *Worktree, gitDir string) {
+	head, err := readTrimmed(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return
+	}
+	ref, symbolic := strings.CutPrefix(head, "ref: ")
+	if !symbolic {
+		wt.Head = head
+		wt.Branch = "(detached)"
+		return
+	}
+	wt.Branch = strings.TrimPrefix(ref, "refs/heads/")
+	if wt.Head, err = b.resolve(ref, gitDir, 0); err != nil {
+		// An unborn branch
+		wt.Head = b.zeroID()
+	}
+}
+
+func (b *nativeBackend) ListRemotes() ([]string, error) {
+	f, err := os.Open(filepath.Join(b.commonDir, "config"))
+	if err != nil {
+		return b.exec.ListRemotes()
+	}
+	defer f.Close()
+
+	var remotes []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[include") {
+			// Remotes may come from other files
+			return b.exec.ListRemotes()
+		}
+		name, ok := strings.CutPrefix(line, `[remote "`)
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, `"]`)
+		if ok && !seen[name] {
+			seen[name] = true
+			remotes = append(remotes, name)
+		}
+	}
+	// Sorted by name like git remote
+	sort.Strings(remotes)
+	return remotes, scanner.Err()
+}
+
+func (b *nativeBackend) ListRefs(prefix string) ([]string, error) {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	refs := make(map[string]bool)
+
+	packed, err := b.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for name := range packed {
+		if strings.HasPrefix(name, prefix) {
+			refs[name] = true
+		}
+	}
+
+	root := filepath.Join(b.commonDir, filepath.FromSlash(prefix))
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.commonDir, path)
+		if err != nil {
+			return err
+		}
+		refs[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *nativeBackend) ResolveRef(ref string) (string, error) {
+	if strings.ContainsAny(ref, "~^:@{} ") {
+		// Revision expressions need git
+		return b.exec.ResolveRef(ref)
+	}
+
+	candidates := []string{ref}
+	if ref != "HEAD" && !strings.HasPrefix(ref, "refs/") {
+		// The order git uses for short names
+		candidates = []string{
+			ref,
+			"refs/" + ref,
+			"refs/tags/" + ref,
+			"refs/heads/" + ref,
+			"refs/remotes/" + ref,
+			"refs/remotes/" + ref + "/HEAD",
+		}
+	}
+	for _, name := range candidates {
+		if commit, err := b.resolve(name, b.gitDir, 0); err == nil && commit != "" {
+			return commit, nil
+		}
+	}
+
+	if isObjectID(ref) {
+		return b.exec.ResolveRef(ref)
+	}
+	return "", fmt.Errorf("unknown ref %s", ref)
+}
+
+func (b *nativeBackend) Status(path string) (*WorktreeStatus, error) {
+	return b.exec.Status(path)
+}
+
+// resolve returns the commit of ref, following symbolic refs. Pseudo refs
+// such as HEAD and per-worktree refs are read from gitDir, others from the
+// common dir and packed-refs.
+func (b *nativeBackend) resolve(ref, gitDir string, depth int) (string, error) {
+	if depth > maxSymrefDepth {
+		return "", fmt.Errorf("symbolic ref loop at %s", ref)
+	}
+
+	dir := b.commonDir
+	if !strings.HasPrefix(ref, "refs/") || strings.HasPrefix(ref, "refs/worktree/") || strings.HasPrefix(ref, "refs/bisect/") {
+		dir = gitDir
+	}
+
+	// Loose refs take precedence over packed ones
+	if content, err := readTrimmed(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+		if target, ok := strings.CutPrefix(content, "ref: "); ok {
+			return b.resolve(target, gitDir, depth+1)
+		}
+		return content, nil
+	}
+
+	packed, err := b.packedRefs()
+	if err != nil {
+		return "", err
+	}
+	if commit, ok := packed[ref]; ok {
+		return commit, nil
+	}
+	return "", fmt.Errorf("unknown ref %s", ref)
+}
+
+// packedRefs reads the packed-refs file of the common dir
+func (b *nativeBackend) packedRefs() (map[string]string, error) {
+	refs := make(map[string]string)
+
+	f, err := os.Open(filepath.Join(b.commonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return refs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			// Header and peeled tags
+			continue
+		}
+		if commit, name, ok := strings.Cut(line, " "); ok {
+			refs[name] = commit
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// configBool reports whether a boolean is set to true in the repository
+// config file
+func (b *nativeBackend) configBool(section, key string) bool {
+	switch strings.ToLower(b.configValue(section, key)) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// configValue returns a value of the config file of the common dir. Only
+// that file is read, without includes.
+func (b *nativeBackend) configValue(section, key string) string {
+	return b.configSection(section)[strings.ToLower(key)]
+}
+
+// configSection returns the values of a section of the config file of the
+// common dir by lower case key. Later values override earlier ones.
+func (b *nativeBackend) configSection(section string) map[string]string {
+	values := make(map[string]string)
+	f, err := os.Open(filepath.Join(b.commonDir, "config"))
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = strings.EqualFold(strings.Trim(line, "[]"), section)
+			continue
+		}
+		if !inSection || line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		// A key without a value is a true boolean
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			v = "true"
+		}
+		values[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// zeroID returns the null object name git reports for unborn branches
+func (b *nativeBackend) zeroID() string {
+	if strings.EqualFold(b.configValue("extensions", "objectformat"), "sha256") {
+		return strings.Repeat("0", 64)
+	}
+	return strings.Repeat("0", 40)
+}
+
+// readTrimmed returns the content of a small file without surrounding space
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// isGitDir reports whether dir looks like a git directory
+func isGitDir(dir string) bool {
+	_, headErr := os.Stat(filepath.Join(dir, "HEAD"))
+	_, objectsErr := os.Stat(filepath.Join(dir, "objects"))
+	return headErr == nil && objectsErr == nil
+}
+
+// isObjectID reports whether s is a full SHA-1 or SHA-256 object name
+func isObjectID(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
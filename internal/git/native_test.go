@@ -0,0 +1,226 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit on main and returns
+// its path. Tests using it are skipped when git is not installed.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "wt")
+	t.Setenv("GIT_AUTHOR_EMAIL", "wt@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "wt")
+	t.Setenv("GIT_COMMITTER_EMAIL", "wt@example.com")
+
+	// Resolve symlinked temp dirs, as git reports real paths
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "repo")
+	runTestGit(t, base, "init", "-q", "-b", "main", root)
+	runTestGit(t, root, "commit", "-q", "--allow-empty", "-m", "init")
+	return root
+}
+
+// runTestGit runs git in dir and fails the test on error
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+// listBoth lists the worktrees of root with the exec and native backends
+func listBoth(t *testing.T, root string) (execList, nativeList []Worktree) {
+	t.Helper()
+	ctx := context.Background()
+
+	execList, err := NewExecBackend(ctx, root).ListWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, err := NewNativeBackend(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nativeList, err = native.ListWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return execList, nativeList
+}
+
+func TestNativeListWorktreesMatchesExec(t *testing.T) {
+	root := newTestRepo(t)
+	base := filepath.Dir(root)
+
+	runTestGit(t, root, "worktree", "add", "-q", "-b", "feature", filepath.Join(base, "feature"))
+	runTestGit(t, root, "worktree", "add", "-q", "--detach", filepath.Join(base, "detached"))
+	runTestGit(t, root, "worktree", "add", "-q", "-b", "locked", filepath.Join(base, "locked"))
+	runTestGit(t, root, "worktree", "lock", "--reason", "on a usb drive", filepath.Join(base, "locked"))
+	runTestGit(t, root, "worktree", "add", "-q", "-b", "gone", filepath.Join(base, "gone"))
+	if err := os.RemoveAll(filepath.Join(base, "gone")); err != nil {
+		t.Fatal(err)
+	}
+	// Refs are read from packed-refs as well as loose files
+	runTestGit(t, root, "pack-refs", "--all")
+
+	for _, dir := range []string{root, filepath.Join(base, "feature")} {
+		execList, nativeList := listBoth(t, dir)
+		if len(execList) != 5 {
+			t.Fatalf("exec backend listed %d worktrees, want 5: %+v", len(execList), execList)
+		}
+		if !reflect.DeepEqual(execList, nativeList) {
+			t.Errorf("from %s:\nexec:   %+v\nnative: %+v", dir, execList, nativeList)
+		}
+	}
+}
+
+func TestNativeListWorktreesRelativeGitdir(t *testing.T) {
+	root := newTestRepo(t)
+	wtPath := filepath.Join(filepath.Dir(root), "feature")
+	runTestGit(t, root, "worktree", "add", "-q", "-b", "feature", wtPath)
+
+	want, _ := listBoth(t, root)
+
+	// Written this way by git with worktree.useRelativePaths
+	gitdir := filepath.Join(root, ".git", "worktrees", "feature", "gitdir")
+	if err := os.WriteFile(gitdir, []byte("../../../../feature/.git\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	native, err := NewNativeBackend(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := native.ListWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %+v\ngot:  %+v", want, got)
+	}
+}
+
+func TestNativeRefsMatchExec(t *testing.T) {
+	root := newTestRepo(t)
+	runTestGit(t, root, "branch", "feature")
+	runTestGit(t, root, "branch", "fix/nested")
+	runTestGit(t, root, "tag", "v1")
+	runTestGit(t, root, "tag", "-a", "-m", "annotated", "v2")
+	runTestGit(t, root, "remote", "add", "upstream", root)
+	runTestGit(t, root, "remote", "add", "origin", root)
+	runTestGit(t, root, "fetch", "-q", "origin")
+	runTestGit(t, root, "remote", "set-head", "origin", "main")
+
+	// Packed refs, with a peeled line for the annotated tag, and loose refs
+	// created or moved afterwards, which take precedence
+	runTestGit(t, root, "pack-refs", "--all")
+	runTestGit(t, root, "commit", "-q", "--allow-empty", "-m", "second")
+	runTestGit(t, root, "branch", "-f", "feature")
+	runTestGit(t, root, "branch", "loose")
+	runTestGit(t, root, "tag", "-a", "-m", "loose annotated", "v3")
+
+	packed, err := os.ReadFile(filepath.Join(root, ".git", "packed-refs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(packed, []byte("\n^")) {
+		t.Fatalf("packed-refs has no peeled tag:\n%s", packed)
+	}
+
+	ctx := context.Background()
+	execBackend := NewExecBackend(ctx, root)
+	native, err := NewNativeBackend(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/", "refs/"} {
+		want, err := execBackend.ListRefs(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := native.ListRefs(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("ListRefs(%q):\nexec:   %v\nnative: %v", prefix, want, got)
+		}
+	}
+
+	for _, ref := range []string{
+		"HEAD", "main", "feature", "refs/heads/feature", "fix/nested", "loose",
+		"v1", "v2", "tags/v2", "refs/tags/v3",
+		"origin/main", "origin", "refs/remotes/origin/HEAD",
+	} {
+		want, err := execBackend.ResolveRef(ref)
+		if err != nil {
+			t.Fatalf("exec ResolveRef(%q): %v", ref, err)
+		}
+		got, err := native.ResolveRef(ref)
+		if err != nil {
+			t.Errorf("native ResolveRef(%q): %v", ref, err)
+			continue
+		}
+		if want != got {
+			t.Errorf("ResolveRef(%q): exec %s, native %s", ref, want, got)
+		}
+	}
+	if _, err := native.ResolveRef("missing"); err == nil {
+		t.Error("native ResolveRef(missing) succeeded")
+	}
+
+	want, err := execBackend.ListRemotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := native.ListRemotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("ListRemotes:\nexec:   %v\nnative: %v", want, got)
+	}
+}
+
+func TestNativeBackendRejectsExtensions(t *testing.T) {
+	root := newTestRepo(t)
+	ctx := context.Background()
+
+	runTestGit(t, root, "config", "extensions.refStorage", "files")
+	if _, err := NewNativeBackend(ctx, root); err != nil {
+		t.Errorf("files ref storage: %v", err)
+	}
+
+	for _, ext := range [][2]string{
+		{"extensions.refStorage", "reftable"},
+		{"extensions.somethingNew", "true"},
+	} {
+		t.Run(ext[0]+"="+ext[1], func(t *testing.T) {
+			root := newTestRepo(t)
+			runTestGit(t, root, "config", ext[0], ext[1])
+			if _, err := NewNativeBackend(ctx, root); err == nil {
+				t.Error("native backend accepted the repository")
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package git
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+// RemoteBranch represents a branch on a remote
+type RemoteBranch struct {
+	Remote string
+	Name   string
+}
+
+// Ref returns the remote-tracking ref name, e.g. origin/main
+func (b RemoteBranch) Ref() string {
+	return b.Remote + "/" + b.Name
+}
+
+// ListRemotes returns the configured remotes
+func (r *Repository) ListRemotes() ([]string, error) {
+	return r.Backend().ListRemotes()
+}
+
+// ListRemoteBranches returns all remote branches
+func (r *Repository) ListRemoteBranches() ([]RemoteBranch, error) {
+	remotes, err := r.ListRemotes()
+	if err != nil {
+		return nil, err
+	}
+	// Match longer remote names first since they may contain slashes
+	sort.Slice(remotes, func(i, j int) bool { return len(remotes[i]) > len(remotes[j]) })
+
+	refs, err := r.Backend().ListRefs("refs/remotes/")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []RemoteBranch
+	for _, line := range refs {
+		ref := strings.TrimPrefix(strings.TrimSpace(line), "refs/remotes/")
+		if ref == "" {
+			continue
+		}
+		for _, remote := range remotes {
+			if name, ok := strings.CutPrefix(ref, remote+"/"); ok {
+				if name != "HEAD" {
+					branches = append(branches, RemoteBranch{Remote: remote, Name: name})
+				}
+				break
+			}
+		}
+	}
+	return branches, nil
+}
+
+// ResolveRemoteBranch finds the remote branch to track for name.
+// name may be a plain branch name or a remote-qualified ref such as
+// upstream/feature. If remote is set, only that remote is considered.
+// When several remotes carry the branch, git's checkout.defaultRemote
+// setting is used to pick one; otherwise the name is ambiguous.
+func (r *Repository) ResolveRemoteBranch(name, remote string) (*RemoteBranch, error) {
+	branches, err := r.ListRemoteBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []RemoteBranch
+	for _, b := range branches {
+		if remote != "" && b.Remote != remote {
+			continue
+		}
+		if b.Ref() == name {
+			return &b, nil
+		}
+		if b.Name == name {
+			candidates = append(candidates, b)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, util.BranchNotFoundError(name)
+	case 1:
+		return &candidates[0], nil
+	}
+
+	if defaultRemote := r.configValue("checkout.defaultRemote"); defaultRemote != "" {
+		for _, b := range candidates {
+			if b.Remote == defaultRemote {
+				return &b, nil
+			}
+		}
+	}
+
+	var refs []string
+	for _, b := range candidates {
+		refs = append(refs, b.Ref())
+	}
+	return nil, util.BranchAmbiguousError(name, refs)
+}
+
+// FetchRemote fetches from remote, or from all remotes if remote is empty.
+// Git does not prompt for credentials, so a remote that needs them fails
+// instead of waiting for input when no credential helper provides them.
+func (r *Repository) FetchRemote(remote string, out io.Writer) error {
+	args := []string{"fetch", "--prune"}
+	if remote == "" {
+		args = append(args, "--all")
+	} else {
+		args = append(args, remote)
+	}
+
+	return runGitAttached(r.Context(), r.RootPath, out, out, args...)
+}
+
+// configValue returns a git config value, or an empty string if unset
+func (r *Repository) configValue(key string) string {
+	output, err := r.run("config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -1,110 +1,191 @@
 package git
 
 import (
+	"context"
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/superkoh/worktree-manager/internal/util"
 )
 
-// Repository represents a git repository
+// BareDirName is the bare repository inside a hub directory created by
+// wt clone, next to a .git file pointing at it and the worktrees
+const BareDirName = ".bare"
+
+// Repository represents a git repository as seen from one of its worktrees
 type Repository struct {
+	// RootPath is the top level of the worktree wt runs in, or the hub
+	// directory when run outside the worktrees of a bare repository
 	RootPath string
-	Name     string
+	// MainPath is the top level of the main worktree. For a bare repository
+	// it is the hub directory holding it, or the bare git directory itself.
+	MainPath string
+	// Name is the repository name, taken from the main worktree
+	Name string
+
+	// ctx ends the git commands run for the repository and its managers
+	ctx     context.Context
+	backend Backend
 }
 
-// DetectRepository finds the git repository root from the current directory
-func DetectRepository() (*Repository, error) {
-	return DetectRepositoryFrom(".")
+// DetectRepository finds the git repository root from the current directory.
+// Git commands run for the repository end when ctx does.
+func DetectRepository(ctx context.Context) (*Repository, error) {
+	return DetectRepositoryFrom(ctx, ".")
 }
 
 // DetectRepositoryFrom finds the git repository root from a given path
-func DetectRepositoryFrom(path string) (*Repository, error) {
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	cmd.Dir = path
-	output, err := cmd.Output()
+func DetectRepositoryFrom(ctx context.Context, path string) (*Repository, error) {
+	output, err := runGit(ctx, path, "rev-parse", "--show-toplevel")
 	if err != nil {
+		if interrupted(ctx, err) {
+			return nil, err
+		}
+		// Outside a work tree, which is fine for a bare repository
+		return detectBareRepository(ctx, path)
+	}
+
+	repo := &Repository{RootPath: strings.TrimSpace(string(output)), ctx: ctx}
+	repo.MainPath = repo.findMainPath()
+	repo.Name = repoName(repo.MainPath)
+	return repo, nil
+}
+
+// detectBareRepository finds a bare repository from a path inside its git
+// directory or its hub directory
+func detectBareRepository(ctx context.Context, path string) (*Repository, error) {
+	output, err := runGit(ctx, path, "rev-parse", "--is-bare-repository", "--absolute-git-dir")
+	if err != nil {
+		if interrupted(ctx, err) {
+			return nil, err
+		}
+		return nil, util.NotGitRepoError()
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 || lines[0] != "true" {
 		return nil, util.NotGitRepoError()
 	}
 
-	rootPath := strings.TrimSpace(string(output))
+	root := bareRoot(lines[1])
 	return &Repository{
-		RootPath: rootPath,
-		Name:     filepath.Base(rootPath),
+		RootPath: root,
+		MainPath: root,
+		Name:     repoName(root),
+		ctx:      ctx,
 	}, nil
 }
 
-// ListBranches returns all local branches
-func (r *Repository) ListBranches() ([]string, error) {
-	cmd := exec.Command("git", "branch", "--format=%(refname:short)")
-	cmd.Dir = r.RootPath
-	output, err := cmd.Output()
+// bareRoot returns the hub directory of a bare repository laid out by
+// wt clone, or gitDir itself for other bare repositories
+func bareRoot(gitDir string) string {
+	if filepath.Base(gitDir) != BareDirName {
+		return gitDir
+	}
+	hub := filepath.Dir(gitDir)
+	if target, err := ReadGitFile(hub); err == nil && filepath.Clean(target) == filepath.Clean(gitDir) {
+		return hub
+	}
+	return gitDir
+}
+
+// repoName returns the repository name for its main path
+func repoName(mainPath string) string {
+	return strings.TrimSuffix(filepath.Base(mainPath), ".git")
+}
+
+// Context returns the context git commands for the repository run with
+func (r *Repository) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// run runs a background git command in the repository root
+func (r *Repository) run(args ...string) ([]byte, error) {
+	return runGit(r.Context(), r.RootPath, args...)
+}
+
+// Backend returns the backend reading the repository, running git unless
+// another one was set
+func (r *Repository) Backend() Backend {
+	if r.backend == nil {
+		r.backend = NewExecBackend(r.Context(), r.RootPath)
+	}
+	return r.backend
+}
+
+// UseNativeBackend reads worktrees and refs from the git directory instead
+// of running git. The exec backend stays in use if that is not possible.
+func (r *Repository) UseNativeBackend() {
+	if b, err := NewNativeBackend(r.Context(), r.RootPath); err == nil {
+		r.backend = b
+	}
+}
+
+// findMainPath returns the main worktree of the repository. It is the parent
+// of a .git common dir, otherwise the first entry git worktree list reports.
+func (r *Repository) findMainPath() string {
+	commonDir, err := r.GetCommonDir()
 	if err != nil {
-		return nil, util.GitCommandError("branch --format", err)
+		return r.RootPath
+	}
+	if filepath.Base(commonDir) == ".git" {
+		return filepath.Dir(commonDir)
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var branches []string
-	for _, line := range lines {
-		if line = strings.TrimSpace(line); line != "" {
-			branches = append(branches, line)
-		}
+	main, err := NewManager(r).GetMainWorktree()
+	if err != nil {
+		return r.RootPath
 	}
-	return branches, nil
+	if main.IsBare {
+		return bareRoot(main.Path)
+	}
+	return main.Path
 }
 
-// ListRemoteBranches returns all remote branches
-func (r *Repository) ListRemoteBranches() ([]string, error) {
-	cmd := exec.Command("git", "branch", "-r", "--format=%(refname:short)")
-	cmd.Dir = r.RootPath
-	output, err := cmd.Output()
+// ListBranches returns all local branches
+func (r *Repository) ListBranches() ([]string, error) {
+	refs, err := r.Backend().ListRefs("refs/heads/")
 	if err != nil {
-		return nil, util.GitCommandError("branch -r", err)
+		return nil, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var branches []string
-	for _, line := range lines {
-		if line = strings.TrimSpace(line); line != "" {
-			// Remove origin/ prefix
-			if strings.HasPrefix(line, "origin/") {
-				line = strings.TrimPrefix(line, "origin/")
-			}
-			if line != "HEAD" {
-				branches = append(branches, line)
-			}
-		}
+	branches := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		branches = append(branches, strings.TrimPrefix(ref, "refs/heads/"))
 	}
 	return branches, nil
 }
 
 // GetCurrentBranch returns the current branch name
 func (r *Repository) GetCurrentBranch() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = r.RootPath
-	output, err := cmd.Output()
+	output, err := r.run("rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
-		return "", util.GitCommandError("rev-parse --abbrev-ref HEAD", err)
+		return "", err
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
 // BranchExists checks if a branch exists
 func (r *Repository) BranchExists(branch string) bool {
-	cmd := exec.Command("git", "rev-parse", "--verify", "refs/heads/"+branch)
-	cmd.Dir = r.RootPath
-	cmd.Stderr = nil
-	cmd.Stdout = nil
-	return cmd.Run() == nil
+	_, err := r.Backend().ResolveRef("refs/heads/" + branch)
+	return err == nil
+}
+
+// CheckBranchName returns an error if name is not a valid branch name
+func (r *Repository) CheckBranchName(name string) error {
+	if _, err := r.run("check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("'%s' is not a valid branch name", name)
+	}
+	return nil
 }
 
 // HasUncommittedChanges checks if there are uncommitted changes
 func (r *Repository) HasUncommittedChanges() bool {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = r.RootPath
-	output, err := cmd.Output()
+	output, err := r.run("status", "--porcelain")
 	if err != nil {
 		return false
 	}
@@ -112,19 +193,14 @@ func (r *Repository) HasUncommittedChanges() bool {
 }
 
 // IsGitRepository checks if a directory is inside a git repository
-func IsGitRepository(path string) bool {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	cmd.Dir = path
-	cmd.Stderr = nil
-	cmd.Stdout = nil
-	return cmd.Run() == nil
+func IsGitRepository(ctx context.Context, path string) bool {
+	_, err := runGit(ctx, path, "rev-parse", "--git-dir")
+	return err == nil
 }
 
 // GetGitDir returns the .git directory path
 func (r *Repository) GetGitDir() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	cmd.Dir = r.RootPath
-	output, err := cmd.Output()
+	output, err := r.run("rev-parse", "--git-dir")
 	if err != nil {
 		return "", err
 	}
@@ -136,11 +212,97 @@ func (r *Repository) GetGitDir() (string, error) {
 	return gitDir, nil
 }
 
-// Fetch fetches from remote
-func (r *Repository) Fetch() error {
-	cmd := exec.Command("git", "fetch", "--all", "--prune")
-	cmd.Dir = r.RootPath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// GetCommonDir returns the git directory shared by all worktrees
+func (r *Repository) GetCommonDir() (string, error) {
+	output, err := r.run("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+
+	commonDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(r.RootPath, commonDir)
+	}
+	return filepath.Clean(commonDir), nil
+}
+
+// DefaultBranch returns the default branch of the repository.
+// It prefers the remote HEAD of origin and falls back to the branch
+// checked out in the main worktree, or the HEAD of a bare repository.
+func (r *Repository) DefaultBranch() (string, error) {
+	if output, err := r.run("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		ref := strings.TrimSpace(string(output))
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+
+	main, err := NewManager(r).GetMainWorktree()
+	if err != nil {
+		return "", err
+	}
+	if main.IsBare {
+		// The HEAD of a bare repository names the branch it was cloned with
+		if output, err := r.run("symbolic-ref", "--short", "HEAD"); err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+	if main.Branch == "" || main.Branch == "(detached)" {
+		return "", fmt.Errorf("cannot determine default branch")
+	}
+	return main.Branch, nil
+}
+
+// ListMergedBranches returns local branches merged into base. A branch
+// without commits of its own, such as one just created from base, is
+// contained in base too but not reported: it has no upstream and no reflog
+// entry besides its creation.
+func (r *Repository) ListMergedBranches(base string) ([]string, error) {
+	output, err := r.run("for-each-ref", "--merged", base, "--format=%(refname:short)\t%(upstream)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	commonDir, err := r.GetCommonDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, line := range strings.Split(string(output), "\n") {
+		name, upstream, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if upstream == "" && reflogEntries(commonDir, "refs/heads/"+name) == 1 {
+			// Created and never committed to
+			continue
+		}
+		branches = append(branches, name)
+	}
+	return branches, nil
+}
+
+// reflogEntries returns the number of reflog entries of ref, or -1 when
+// the ref has no reflog
+func reflogEntries(commonDir, ref string) int {
+	data, err := os.ReadFile(filepath.Join(commonDir, "logs", filepath.FromSlash(ref)))
+	if err != nil {
+		return -1
+	}
+	return len(splitLines(string(data)))
+}
+
+// ListGoneBranches returns local branches whose upstream no longer exists
+func (r *Repository) ListGoneBranches() ([]string, error) {
+	output, err := r.run("for-each-ref", "--format=%(refname:short)\t%(upstream:track)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, line := range strings.Split(string(output), "\n") {
+		name, track, ok := strings.Cut(line, "\t")
+		if ok && track == "[gone]" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
 }
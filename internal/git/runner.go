@@ -0,0 +1,119 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+// DefaultTimeout bounds git commands that only read the repository
+const DefaultTimeout = time.Minute
+
+// waitDelay is how long a killed git command may keep its output open,
+// for example through a hook it started, before wt stops waiting for it
+const waitDelay = time.Second
+
+// TimeoutEnv is the environment variable overriding DefaultTimeout. It holds
+// a time.ParseDuration value such as 90s or 5m; 0 turns the timeout off.
+const TimeoutEnv = "WT_GIT_TIMEOUT"
+
+// commandTimeout returns the timeout of background git commands
+func commandTimeout() time.Duration {
+	if v := os.Getenv(TimeoutEnv); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return DefaultTimeout
+}
+
+// runGit runs a background git command in dir and returns its output.
+// Credential prompts are disabled since nobody would see them, the command
+// is killed when ctx ends or the timeout expires, and its stderr is part of
+// the returned error.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	timeout := commandTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.WaitDelay = waitDelay
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+			return output, util.GitTimeoutError(commandName(args), timeout)
+		}
+		return output, util.GitCommandErrorWithOutput(commandName(args), err, stderr.String())
+	}
+	return output, nil
+}
+
+// runGitAttached runs a git command in dir that changes the repository or
+// talks to a remote. It may take long, so only ctx ends it. Output goes to
+// stdout and stderr; when stderr is nil it is captured into the returned
+// error. Credential prompts are disabled, leaving credentials to helpers.
+func runGitAttached(ctx context.Context, dir string, stdout, stderr io.Writer, args ...string) error {
+	return attachedCommand(ctx, dir, stdout, stderr, false, args...)
+}
+
+// runGitInteractive is runGitAttached for commands the user waits for and
+// may have to enter credentials for, such as the clone of wt clone
+func runGitInteractive(ctx context.Context, dir string, out io.Writer, args ...string) error {
+	return attachedCommand(ctx, dir, out, out, true, args...)
+}
+
+// attachedCommand runs git for runGitAttached and runGitInteractive
+func attachedCommand(ctx context.Context, dir string, stdout, stderr io.Writer, prompt bool, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.WaitDelay = waitDelay
+	if !prompt {
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	}
+
+	var captured bytes.Buffer
+	if stderr != nil {
+		cmd.Stderr = stderr
+	} else {
+		cmd.Stderr = &captured
+	}
+
+	if err := cmd.Run(); err != nil {
+		return util.GitCommandErrorWithOutput(commandName(args), err, captured.String())
+	}
+	return nil
+}
+
+// interrupted reports whether a git command failed because ctx ended or
+// it timed out, rather than because of what git found
+func interrupted(ctx context.Context, err error) bool {
+	var wtErr *util.WTError
+	return ctx.Err() != nil || (errors.As(err, &wtErr) && wtErr.Code == util.ErrGitTimeout)
+}
+
+// commandName names a git command in errors, such as "worktree add"
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if len(args) > 1 && !strings.ContainsAny(args[1], "=%/") {
+		return args[0] + " " + args[1]
+	}
+	return args[0]
+}
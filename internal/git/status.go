@@ -0,0 +1,157 @@
+package git
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorktreeStatus holds the working tree and upstream state of a worktree
+type WorktreeStatus struct {
+	Staged            int
+	Unstaged          int
+	Untracked         int
+	Upstream          string
+	Ahead             int
+	Behind            int
+	LastCommitSubject string
+	LastCommitTime    time.Time
+}
+
+// IsDirty reports whether the worktree has any local changes
+func (s *WorktreeStatus) IsDirty() bool {
+	return s.Staged > 0 || s.Unstaged > 0 || s.Untracked > 0
+}
+
+// LoadStatus populates the Status field of each worktree.
+// Worktrees are inspected in parallel; bare and prunable entries are skipped.
+func (m *Manager) LoadStatus(worktrees []Worktree) {
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+
+	for i := range worktrees {
+		if worktrees[i].IsBare || worktrees[i].IsPrunable {
+			continue
+		}
+		wg.Add(1)
+		go func(wt *Worktree) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			status, err := m.repo.Backend().Status(wt.Path)
+			if err == nil {
+				wt.Status = status
+			}
+		}(&worktrees[i])
+	}
+
+	wg.Wait()
+}
+
+// GetStatus returns the status of the worktree at path
+func GetStatus(ctx context.Context, path string) (*WorktreeStatus, error) {
+	output, err := runGit(ctx, path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return nil, err
+	}
+
+	status := parseStatus(string(output))
+
+	output, err = runGit(ctx, path, "log", "-1", "--format=%ct%x00%s")
+	if err == nil {
+		// An unborn branch has no commits; leave the fields empty
+		parts := strings.SplitN(strings.TrimSpace(string(output)), "\x00", 2)
+		if len(parts) == 2 {
+			if ts, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
+				status.LastCommitTime = time.Unix(ts, 0)
+			}
+			status.LastCommitSubject = parts[1]
+		}
+	}
+
+	return status, nil
+}
+
+// parseStatus parses the output of git status --porcelain=v2 --branch
+func parseStatus(output string) *WorktreeStatus {
+	status := &WorktreeStatus{}
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "):
+			if len(line) < 4 {
+				continue
+			}
+			if line[2] != '.' {
+				status.Staged++
+			}
+			if line[3] != '.' {
+				status.Unstaged++
+			}
+		case strings.HasPrefix(line, "u "):
+			status.Unstaged++
+		case strings.HasPrefix(line, "? "):
+			status.Untracked++
+		}
+	}
+
+	return status
+}
+
+// ShortStatus returns the lines of git status --short for the worktree at path
+func ShortStatus(ctx context.Context, path string) ([]string, error) {
+	output, err := runGit(ctx, path, "status", "--short")
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(string(output)), nil
+}
+
+// RecentCommits returns up to n one-line summaries of the latest commits
+// in the worktree at path, newest first
+func RecentCommits(ctx context.Context, path string, n int) ([]string, error) {
+	output, err := runGit(ctx, path, "log", "-n", strconv.Itoa(n), "--format=%h %s (%cr)")
+	if err != nil {
+		if interrupted(ctx, err) {
+			return nil, err
+		}
+		// An unborn branch has no commits to show
+		return nil, nil
+	}
+
+	return splitLines(string(output)), nil
+}
+
+// splitLines splits command output into non-empty lines
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// IsTracked reports whether rel, or any file below it, is tracked by git
+// in the worktree at path
+func IsTracked(ctx context.Context, path, rel string) (bool, error) {
+	output, err := runGit(ctx, path, "ls-files", "-z", "--", rel)
+	if err != nil {
+		return false, err
+	}
+	return len(output) > 0, nil
+}
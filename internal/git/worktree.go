@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -19,13 +18,18 @@ type Worktree struct {
 	Head       string
 	IsBare     bool
 	IsLocked   bool
+	LockReason string `json:",omitempty"`
 	IsPrunable bool
 	IsCurrent  bool
+	Status     *WorktreeStatus `json:",omitempty"`
 }
 
-// Manager handles worktree operations
+// Manager handles worktree operations. Its git commands run with the
+// context of the repository.
 type Manager struct {
 	repo *Repository
+	// worktrees caches the list until an operation changes it
+	worktrees []Worktree
 }
 
 // NewManager creates a new worktree manager
@@ -33,24 +37,22 @@ func NewManager(repo *Repository) *Manager {
 	return &Manager{repo: repo}
 }
 
-// List returns all worktrees
+// List returns all worktrees. The list is read once and reused until the
+// manager changes a worktree.
 func (m *Manager) List() ([]Worktree, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = m.repo.RootPath
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, util.GitCommandError("worktree list", err)
-	}
-
-	worktrees, err := parseWorktreeList(string(output))
-	if err != nil {
-		return nil, err
+	if m.worktrees == nil {
+		worktrees, err := m.repo.Backend().ListWorktrees()
+		if err != nil {
+			return nil, err
+		}
+		m.worktrees = worktrees
 	}
+	worktrees := append([]Worktree(nil), m.worktrees...)
 
 	// Mark current worktree
 	cwd, _ := os.Getwd()
 	for i := range worktrees {
-		if strings.HasPrefix(cwd, worktrees[i].Path) {
+		if isWithin(cwd, worktrees[i].Path) {
 			worktrees[i].IsCurrent = true
 		}
 	}
@@ -81,53 +83,143 @@ func (m *Manager) Add(path, branch string, createBranch bool, quiet bool) error
 		args = append(args, absPath, branch)
 	}
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = m.repo.RootPath
+	return m.runWorktreeAdd(args, quiet)
+}
 
-	var stderrBuf bytes.Buffer
-	if !quiet {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-	} else {
-		cmd.Stderr = &stderrBuf
+// AddTracking creates a new worktree on a new local branch that tracks
+// the given remote branch
+func (m *Manager) AddTracking(path string, remote RemoteBranch, quiet bool) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
 	}
 
-	if err := cmd.Run(); err != nil {
-		if quiet && stderrBuf.Len() > 0 {
-			return util.GitCommandErrorWithOutput("worktree add", err, stderrBuf.String())
-		}
-		return util.GitCommandError("worktree add", err)
+	if util.FileExists(absPath) {
+		return util.WorktreeExistsError(absPath)
+	}
+
+	args := []string{"worktree", "add", "--track", "-b", remote.Name, absPath, remote.Ref()}
+	return m.runWorktreeAdd(args, quiet)
+}
+
+// runWorktreeAdd runs git worktree add with the given arguments
+func (m *Manager) runWorktreeAdd(args []string, quiet bool) error {
+	m.worktrees = nil
+
+	if quiet {
+		return m.run(m.repo.RootPath, args...)
 	}
+	return m.runAttached(args...)
+}
 
-	return nil
+// run runs a git command changing worktrees in dir. Its output is
+// discarded and stderr is part of the returned error.
+func (m *Manager) run(dir string, args ...string) error {
+	return runGitAttached(m.repo.Context(), dir, nil, nil, args...)
 }
 
-// Remove removes a worktree
-func (m *Manager) Remove(path string, force bool) error {
+// runAttached runs a git command in the repository root with its output
+// going to the terminal
+func (m *Manager) runAttached(args ...string) error {
+	return runGitAttached(m.repo.Context(), m.repo.RootPath, os.Stdout, os.Stderr, args...)
+}
+
+// Remove removes a worktree.
+// A force level of 1 removes worktrees with uncommitted changes;
+// 2 also removes locked worktrees.
+func (m *Manager) Remove(path string, force int) error {
+	m.worktrees = nil
+
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return err
 	}
 
 	args := []string{"worktree", "remove"}
-	if force {
+	for i := 0; i < force && i < 2; i++ {
 		args = append(args, "--force")
 	}
 	args = append(args, absPath)
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = m.repo.RootPath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return util.GitCommandError("worktree remove", err)
+	return m.runAttached(args...)
+}
+
+// Move moves a worktree to a new path
+func (m *Manager) Move(path, newPath string) error {
+	m.worktrees = nil
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	absNewPath, err := filepath.Abs(newPath)
+	if err != nil {
+		return err
+	}
+
+	if util.FileExists(absNewPath) {
+		return util.WorktreeExistsError(absNewPath)
+	}
+
+	return m.run(m.repo.RootPath, "worktree", "move", absPath, absNewPath)
+}
+
+// RenameBranch renames the branch checked out in the worktree at path
+func (m *Manager) RenameBranch(path, oldBranch, newBranch string) error {
+	m.worktrees = nil
+
+	if m.repo.BranchExists(newBranch) {
+		return fmt.Errorf("branch '%s' already exists", newBranch)
+	}
+
+	// Run inside the worktree so git updates its HEAD
+	return m.run(path, "branch", "-m", oldBranch, newBranch)
+}
+
+// Lock locks a worktree so it cannot be pruned, moved or removed
+func (m *Manager) Lock(path, reason string) error {
+	m.worktrees = nil
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"worktree", "lock"}
+	if reason != "" {
+		args = append(args, "--reason", reason)
+	}
+	args = append(args, absPath)
+
+	return m.run(m.repo.RootPath, args...)
+}
+
+// Unlock unlocks a worktree
+func (m *Manager) Unlock(path string) error {
+	m.worktrees = nil
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return m.run(m.repo.RootPath, "worktree", "unlock", absPath)
+}
+
+// Repair repairs the administrative files linking worktrees and the
+// repository. Paths of worktrees that were moved manually may be given.
+func (m *Manager) Repair(paths ...string) error {
+	m.worktrees = nil
+
+	args := append([]string{"worktree", "repair"}, paths...)
+
+	return m.run(m.repo.RootPath, args...)
 }
 
 // Prune removes worktree information for worktrees that are no longer present
 func (m *Manager) Prune(dryRun bool) ([]string, error) {
+	m.worktrees = nil
+
 	args := []string{"worktree", "prune"}
 	if dryRun {
 		args = append(args, "--dry-run", "-v")
@@ -135,16 +227,16 @@ func (m *Manager) Prune(dryRun bool) ([]string, error) {
 		args = append(args, "-v")
 	}
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = m.repo.RootPath
-	output, err := cmd.CombinedOutput()
+	// Prune reports on stderr
+	var output bytes.Buffer
+	err := runGitAttached(m.repo.Context(), m.repo.RootPath, &output, &output, args...)
 	if err != nil {
-		return nil, util.GitCommandError("worktree prune", err)
+		return nil, err
 	}
 
 	// Parse output for pruned worktrees
 	var pruned []string
-	lines := strings.Split(string(output), "\n")
+	lines := strings.Split(output.String(), "\n")
 	for _, line := range lines {
 		if strings.TrimSpace(line) != "" {
 			pruned = append(pruned, line)
@@ -210,11 +302,41 @@ func (m *Manager) DeleteBranch(branch string, force bool) error {
 		flag = "-D"
 	}
 
-	cmd := exec.Command("git", "branch", flag, branch)
-	cmd.Dir = m.repo.RootPath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return m.runAttached("branch", flag, branch)
+}
+
+// ReadGitFile returns the git directory a linked worktree at path points
+// to through its .git file
+func ReadGitFile(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return "", err
+	}
+
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s: not a gitdir file", filepath.Join(path, ".git"))
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(path, gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// WorktreeGitDir returns the git directory of the worktree at path: the
+// .git directory of a main worktree or the one a linked worktree points to
+func WorktreeGitDir(path string) (string, error) {
+	gitPath := filepath.Join(path, ".git")
+	if util.IsDirectory(gitPath) {
+		return gitPath, nil
+	}
+	return ReadGitFile(path)
+}
+
+// isWithin reports whether path is dir or lies inside it
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
 }
 
 // parseWorktreeList parses the porcelain output of git worktree list
@@ -241,6 +363,9 @@ func parseWorktreeList(output string) ([]Worktree, error) {
 			current.IsBare = true
 		case line == "locked":
 			current.IsLocked = true
+		case strings.HasPrefix(line, "locked "):
+			current.IsLocked = true
+			current.LockReason = strings.TrimPrefix(line, "locked ")
 		case strings.HasPrefix(line, "prunable"):
 			current.IsPrunable = true
 		case line == "detached":
@@ -0,0 +1,132 @@
+package hooks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+// Event identifies a worktree lifecycle point
+type Event string
+
+const (
+	PostCreate Event = "post-create"
+	PreRemove  Event = "pre-remove"
+	PostRemove Event = "post-remove"
+	PostSelect Event = "post-select"
+)
+
+// Context describes the worktree a hook runs for
+type Context struct {
+	WorktreePath string
+	Branch       string
+	RepoRoot     string
+	MainWorktree string
+	// Ports holds the ports allocated to the worktree by name
+	Ports map[string]int
+}
+
+// Environ returns the environment variables exported to hook commands.
+// Each allocated port is exported as WT_PORT_<NAME>, e.g. WT_PORT_WEB.
+func (c Context) Environ(event Event) []string {
+	env := []string{
+		"WT_HOOK=" + string(event),
+		"WT_WORKTREE_PATH=" + c.WorktreePath,
+		"WT_BRANCH=" + c.Branch,
+		"WT_REPO_ROOT=" + c.RepoRoot,
+		"WT_MAIN_WORKTREE=" + c.MainWorktree,
+	}
+
+	names := make([]string, 0, len(c.Ports))
+	for name := range c.Ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, fmt.Sprintf("%s=%d", PortEnvName(name), c.Ports[name]))
+	}
+
+	return env
+}
+
+// PortEnvName returns the environment variable holding the named port
+func PortEnvName(name string) string {
+	return "WT_PORT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Commands returns the configured commands for an event
+func Commands(cfg *config.Config, event Event) []string {
+	switch event {
+	case PostCreate:
+		return cfg.Hooks.PostCreate
+	case PreRemove:
+		return cfg.Hooks.PreRemove
+	case PostRemove:
+		return cfg.Hooks.PostRemove
+	case PostSelect:
+		return cfg.Hooks.PostSelect
+	}
+	return nil
+}
+
+// Run executes the commands configured for event.
+// Output is streamed to stdout, or to stderr when quiet is set so that
+// stdout stays reserved for shell integration. A failing command returns
+// an error only when hooks.on-failure is "abort"; otherwise a warning is
+// printed and the remaining commands still run.
+func Run(cfg *config.Config, event Event, ctx Context, quiet bool) error {
+	commands := Commands(cfg, event)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	if quiet {
+		out = os.Stderr
+	}
+
+	// Hooks run inside the worktree, unless it no longer exists
+	dir := ctx.WorktreePath
+	if !util.IsDirectory(dir) {
+		dir = ctx.RepoRoot
+	}
+	if !util.IsDirectory(dir) {
+		dir = ctx.MainWorktree
+	}
+
+	fmt.Fprintf(out, "Running %s hooks...\n", event)
+	for _, command := range commands {
+		fmt.Fprintf(out, "  $ %s\n", command)
+
+		cmd := shellCommand(command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), ctx.Environ(event)...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			if cfg.Hooks.OnFailure == config.HookFailureAbort {
+				return fmt.Errorf("%s hook %q failed: %w", event, command, err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %s hook %q failed: %v\n", event, command, err)
+		}
+	}
+
+	return nil
+}
+
+// shellCommand builds a command that runs s through the platform shell
+func shellCommand(s string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", s)
+	}
+	return exec.Command("sh", "-c", s)
+}
@@ -0,0 +1,249 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/util"
+)
+
+// RegistryFileName is the registry file inside the wt directory of the
+// git common dir, shared by all worktrees of a repository
+const RegistryFileName = "resources.json"
+
+// Registry records the resources allocated to each worktree
+type Registry struct {
+	// Ports maps a worktree path to its ports by name
+	Ports map[string]map[string]int `json:"ports"`
+
+	path string
+}
+
+// RegistryPath returns the registry location for a git common dir
+func RegistryPath(commonDir string) string {
+	return filepath.Join(commonDir, "wt", RegistryFileName)
+}
+
+// Load reads the registry of the repository with the given common dir.
+// A missing registry is empty.
+func Load(commonDir string) (*Registry, error) {
+	r := &Registry{path: RegistryPath(commonDir)}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, fmt.Errorf("invalid resource registry %s: %w", r.path, err)
+		}
+	}
+
+	if r.Ports == nil {
+		r.Ports = make(map[string]map[string]int)
+	}
+	return r, nil
+}
+
+// Lock timing: how long Update waits for another process to release the
+// registry, and the age after which a lock left by a killed process is broken
+const (
+	lockTimeout = 10 * time.Second
+	lockStale   = time.Minute
+)
+
+// Update loads the registry, applies fn and saves the result, holding a
+// lock file so that concurrent wt processes don't allocate the same port or
+// drop each other's changes. Nothing is written when fn fails or leaves
+// the registry unchanged.
+func Update(commonDir string, fn func(*Registry) error) error {
+	unlock, err := lock(RegistryPath(commonDir) + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r, err := Load(commonDir)
+	if err != nil {
+		return err
+	}
+	before, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := fn(r); err != nil {
+		return err
+	}
+	after, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	return r.Save()
+}
+
+// lock creates the lock file at path, waiting while another process holds
+// it, and returns a function removing it
+func lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			// Left behind by a process that was killed
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("resource registry is locked by another wt process (remove %s if none is running)", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Save writes the registry, replacing the previous file atomically.
+// Use Update to change the registry while other processes may use it.
+func (r *Registry) Save() error {
+	dir := filepath.Dir(r.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, RegistryFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes the file private
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
+
+// AllocatePorts assigns a port from each configured range to the worktree.
+// Ports it already holds are kept. A new port is neither allocated to
+// another worktree nor in use on this machine.
+func (r *Registry) AllocatePorts(worktree string, ranges map[string]config.PortRange) (map[string]int, error) {
+	used := make(map[int]bool)
+	for path, ports := range r.Ports {
+		if path == worktree {
+			continue
+		}
+		for _, port := range ports {
+			used[port] = true
+		}
+	}
+
+	allocated := make(map[string]int, len(ranges))
+	for name, port := range r.Ports[worktree] {
+		if _, ok := ranges[name]; ok {
+			allocated[name] = port
+			used[port] = true
+		}
+	}
+
+	// Allocate in name order so results are reproducible
+	names := make([]string, 0, len(ranges))
+	for name := range ranges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := allocated[name]; ok {
+			continue
+		}
+
+		rng := ranges[name]
+		port := 0
+		for p := rng.Min; p <= rng.Max; p++ {
+			if !used[p] && isPortFree(p) {
+				port = p
+				break
+			}
+		}
+		if port == 0 {
+			return nil, util.PortsExhaustedError(name, rng.Min, rng.Max)
+		}
+
+		allocated[name] = port
+		used[port] = true
+	}
+
+	if len(allocated) > 0 {
+		r.Ports[worktree] = allocated
+	}
+	return allocated, nil
+}
+
+// Release frees all resources of a worktree
+func (r *Registry) Release(worktree string) {
+	delete(r.Ports, worktree)
+}
+
+// Rename moves the resources of a worktree to its new path
+func (r *Registry) Rename(oldPath, newPath string) {
+	if ports, ok := r.Ports[oldPath]; ok {
+		delete(r.Ports, oldPath)
+		r.Ports[newPath] = ports
+	}
+}
+
+// Prune frees the resources of worktrees not in registered and returns
+// their paths
+func (r *Registry) Prune(registered map[string]bool) []string {
+	var pruned []string
+	for path := range r.Ports {
+		if !registered[path] {
+			pruned = append(pruned, path)
+			delete(r.Ports, path)
+		}
+	}
+	sort.Strings(pruned)
+	return pruned
+}
+
+// isPortFree reports whether a TCP port can be bound on this machine
+func isPortFree(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
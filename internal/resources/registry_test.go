@@ -0,0 +1,75 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+)
+
+func TestUpdateAllocatesConcurrently(t *testing.T) {
+	commonDir := t.TempDir()
+	ranges := map[string]config.PortRange{"web": {Min: 41000, Max: 41999}}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(commonDir, func(r *Registry) error {
+				_, err := r.AllocatePorts(fmt.Sprintf("/worktrees/%d", i), ranges)
+				return err
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := Load(commonDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Ports) != workers {
+		t.Fatalf("registry holds %d worktrees, want %d: %v", len(r.Ports), workers, r.Ports)
+	}
+	seen := make(map[int]string)
+	for path, ports := range r.Ports {
+		if other, ok := seen[ports["web"]]; ok {
+			t.Errorf("port %d allocated to %s and %s", ports["web"], other, path)
+		}
+		seen[ports["web"]] = path
+	}
+
+	// The lock and temporary files are gone
+	entries, err := os.ReadDir(filepath.Dir(RegistryPath(commonDir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != RegistryFileName {
+		t.Errorf("unexpected files next to the registry: %v", entries)
+	}
+}
+
+func TestUpdateWithoutChangesWritesNothing(t *testing.T) {
+	commonDir := t.TempDir()
+	err := Update(commonDir, func(r *Registry) error {
+		r.Release("/worktrees/gone")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(RegistryPath(commonDir)); !os.IsNotExist(err) {
+		t.Errorf("registry was written: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package setup
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Link problems reported by CheckLinks
+const (
+	LinkMissing  = "missing"
+	LinkDangling = "dangling"
+)
+
+// LinkIssue describes a setup link that is not in the expected state
+type LinkIssue struct {
+	Path    string
+	Problem string
+	// SourceExists is false when the link target no longer exists in srcBase
+	SourceExists bool
+}
+
+// CheckLinks inspects the links that LinkPaths would create in dstBase and
+// reports the ones that are missing or point to a path that doesn't exist
+func CheckLinks(srcBase, dstBase string, patterns []string) ([]LinkIssue, error) {
+	expected, err := ExpandPatterns(srcBase, patterns, true)
+	if err != nil {
+		return nil, err
+	}
+	present, err := ExpandPatterns(dstBase, patterns, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LinkIssue
+	checked := make(map[string]bool)
+
+	for _, p := range expected {
+		if _, err := os.Stat(filepath.Join(srcBase, p)); err != nil {
+			continue
+		}
+		checked[p] = true
+
+		dst := filepath.Join(dstBase, p)
+		if _, err := os.Lstat(dst); os.IsNotExist(err) {
+			issues = append(issues, LinkIssue{Path: p, Problem: LinkMissing, SourceExists: true})
+		} else if IsSymlink(dst) && !targetExists(dst) {
+			issues = append(issues, LinkIssue{Path: p, Problem: LinkDangling, SourceExists: true})
+		}
+	}
+
+	// Links left behind after their source was removed
+	for _, p := range present {
+		if checked[p] {
+			continue
+		}
+		dst := filepath.Join(dstBase, p)
+		if IsSymlink(dst) && !targetExists(dst) {
+			issues = append(issues, LinkIssue{Path: p, Problem: LinkDangling})
+		}
+	}
+
+	return issues, nil
+}
+
+// targetExists reports whether the target of the symlink at path exists
+func targetExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
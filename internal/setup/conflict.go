@@ -0,0 +1,138 @@
+package setup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+)
+
+// BackupSuffix is appended to destinations moved aside by the backup policy
+const BackupSuffix = ".wt-backup"
+
+// prepareDestination applies the entry policy to whatever already exists at
+// the destination of rel. It returns false when the destination has to be
+// kept. Paths tracked by git are never replaced unless the entry allows it,
+// and symlinks are removed so nothing is written through them.
+func prepareDestination(ctx context.Context, dstBase, rel string, entry config.SetupEntry, quiet bool) (bool, error) {
+	dst := filepath.Join(dstBase, rel)
+	info, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !entry.AllowTracked {
+		tracked, err := git.IsTracked(ctx, dstBase, rel)
+		if err != nil {
+			return false, err
+		}
+		if tracked {
+			if !quiet {
+				fmt.Printf("  preserved (tracked by git): %s\n", rel)
+			}
+			return false, nil
+		}
+	}
+
+	switch entry.EffectivePolicy() {
+	case config.PolicySkip:
+		if !quiet {
+			fmt.Printf("  preserved (exists): %s\n", rel)
+		}
+		return false, nil
+	case config.PolicyFail:
+		return false, fmt.Errorf("%s already exists in the worktree", rel)
+	case config.PolicyBackup:
+		backup, err := backupPath(dst)
+		if err != nil {
+			return false, err
+		}
+		if err := os.Rename(dst, backup); err != nil {
+			return false, fmt.Errorf("failed to back up %s: %w", rel, err)
+		}
+		if !quiet {
+			fmt.Printf("  backed up: %s -> %s\n", rel, filepath.Base(backup))
+		}
+		return true, nil
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(dst); err != nil {
+			return false, fmt.Errorf("failed to remove link %s: %w", rel, err)
+		}
+	}
+	return true, nil
+}
+
+// backupPath returns an unused backup name for path
+func backupPath(path string) (string, error) {
+	backup := path + BackupSuffix
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			return backup, nil
+		} else if err != nil {
+			return "", err
+		}
+		backup = fmt.Sprintf("%s%s.%d", path, BackupSuffix, i)
+	}
+}
+
+// mergeEnv returns dst with the variables of src that dst doesn't define
+// appended, and the names of the added variables. Existing lines,
+// comments and values in dst are kept as they are.
+func mergeEnv(dst, src []byte) ([]byte, []string) {
+	defined := make(map[string]bool)
+	for _, line := range strings.Split(string(dst), "\n") {
+		if key := envKey(line); key != "" {
+			defined[key] = true
+		}
+	}
+
+	var added []string
+	var extra bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for scanner.Scan() {
+		line := scanner.Text()
+		key := envKey(line)
+		if key == "" || defined[key] {
+			continue
+		}
+		defined[key] = true
+		added = append(added, key)
+		extra.WriteString(line)
+		extra.WriteByte('\n')
+	}
+
+	if len(added) == 0 {
+		return dst, nil
+	}
+
+	merged := append([]byte{}, dst...)
+	if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+		merged = append(merged, '\n')
+	}
+	return append(merged, extra.Bytes()...), added
+}
+
+// envKey returns the variable name assigned on a dotenv line, if any
+func envKey(line string) string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ""
+	}
+	line = strings.TrimPrefix(line, "export ")
+	key, _, ok := strings.Cut(line, "=")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
@@ -1,15 +1,27 @@
 package setup
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
 )
 
-// CopyFiles copies specified paths from source to destination
-func CopyFiles(srcBase, dstBase string, paths []string, quiet bool) error {
-	for _, p := range paths {
+// CopyFiles copies paths matching the given entries from source to
+// destination, as copies, reflinks or hardlinks depending on the entry mode.
+// Existing destinations are handled by the entry policy.
+func CopyFiles(ctx context.Context, srcBase, dstBase string, entries []config.SetupEntry, quiet bool) error {
+	matches, err := ExpandEntries(srcBase, entries, quiet)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		p := m.Path
 		src := filepath.Join(srcBase, p)
 		dst := filepath.Join(dstBase, p)
 
@@ -25,61 +37,186 @@ func CopyFiles(srcBase, dstBase string, paths []string, quiet bool) error {
 			return fmt.Errorf("failed to stat %s: %w", p, err)
 		}
 
+		if m.Entry.Policy == config.PolicyMergeEnv && !info.IsDir() && isRegularFile(dst) {
+			if err := mergeEnvFile(ctx, src, dst, p, m.Entry, dstBase, quiet); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ok, err := prepareDestination(ctx, dstBase, p, m.Entry, quiet)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
 		// Ensure parent directory exists
 		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 			return fmt.Errorf("failed to create directory for %s: %w", p, err)
 		}
 
+		c := &copier{mode: m.Entry.Mode}
 		if info.IsDir() {
-			if err := copyDir(src, dst); err != nil {
+			if err := c.copyDir(src, dst); err != nil {
 				return fmt.Errorf("failed to copy directory %s: %w", p, err)
 			}
 		} else {
-			if err := copyFile(src, dst); err != nil {
+			if err := c.copyFile(src, dst); err != nil {
 				return fmt.Errorf("failed to copy file %s: %w", p, err)
 			}
 		}
 		if !quiet {
-			fmt.Printf("  copied: %s\n", p)
+			fmt.Printf("  %s: %s\n", c.verb(), p)
 		}
 	}
 	return nil
 }
 
-// copyFile copies a single file
+// mergeEnvFile appends the variables of the dotenv file src that the
+// existing dst lacks
+func mergeEnvFile(ctx context.Context, src, dst, rel string, entry config.SetupEntry, dstBase string, quiet bool) error {
+	if !entry.AllowTracked {
+		tracked, err := git.IsTracked(ctx, dstBase, rel)
+		if err != nil {
+			return err
+		}
+		if tracked {
+			if !quiet {
+				fmt.Printf("  preserved (tracked by git): %s\n", rel)
+			}
+			return nil
+		}
+	}
+
+	srcData, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	dstData, err := os.ReadFile(dst)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(dst)
+	if err != nil {
+		return err
+	}
+
+	merged, added := mergeEnv(dstData, srcData)
+	if len(added) > 0 {
+		// Keep the mode, a dotenv file with secrets is often private
+		if err := replaceFile(dst, merged, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to merge %s: %w", rel, err)
+		}
+	}
+	if !quiet {
+		fmt.Printf("  merged: %s (%d new variable(s))\n", rel, len(added))
+	}
+	return nil
+}
+
+// isRegularFile reports whether path is a regular file, not following links
+func isRegularFile(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// copier creates files and directories with a setup mode. It notes when
+// the mode is not supported and files are copied instead.
+type copier struct {
+	mode     string
+	fellBack bool
+}
+
+// verb describes how the last path was created, for setup output
+func (c *copier) verb() string {
+	switch {
+	case c.fellBack:
+		return fmt.Sprintf("copied (%s not supported)", c.mode)
+	case c.mode == config.ModeReflink:
+		return "cloned"
+	case c.mode == config.ModeHardlink:
+		return "hardlinked"
+	}
+	return "copied"
+}
+
+// copyFile copies a single file, keeping its mode and modification time
 func copyFile(src, dst string) error {
+	c := &copier{mode: config.ModeCopy}
+	return c.copyFile(src, dst)
+}
+
+// copyDir recursively copies a directory, see copier.copyDir
+func copyDir(src, dst string) error {
+	c := &copier{mode: config.ModeCopy}
+	return c.copyDir(src, dst)
+}
+
+// copyFile creates dst from the file src. An existing dst is replaced, not
+// written through, so a hardlink to src is never truncated.
+func (c *copier) copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if c.mode == config.ModeHardlink {
+		if err := os.Link(src, dst); err == nil {
+			return nil
+		}
+		// Most likely another file system
+		c.fellBack = true
+	}
+
 	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
 
-	// Get source file info for permissions
-	info, err := in.Stat()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
 	if err != nil {
 		return err
 	}
 
-	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
-	if err != nil {
+	cloned := false
+	if c.mode == config.ModeReflink {
+		if err := cloneFile(out, in); err == nil {
+			cloned = true
+		} else {
+			c.fellBack = true
+		}
+	}
+	if !cloned {
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
 		return err
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, in)
-	return err
+	return preserveAttributes(dst, info)
 }
 
-// copyDir recursively copies a directory
-func copyDir(src, dst string) error {
-	// Get source directory info
+// copyDir recursively copies a directory. Symlinks inside it are recreated
+// with the same target instead of being followed, and the modes and
+// modification times of files and directories are kept.
+func (c *copier) copyDir(src, dst string) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
 	}
 
 	// Create destination directory
-	if err := os.MkdirAll(dst, srcInfo.Mode()); err != nil {
+	if err := os.MkdirAll(dst, srcInfo.Mode().Perm()|0700); err != nil {
 		return err
 	}
 
@@ -92,16 +229,53 @@ func copyDir(src, dst string) error {
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
-		if entry.IsDir() {
-			if err := copyDir(srcPath, dstPath); err != nil {
+		switch {
+		case entry.Type()&os.ModeSymlink != 0:
+			target, err := os.Readlink(srcPath)
+			if err != nil {
 				return err
 			}
-		} else {
-			if err := copyFile(srcPath, dstPath); err != nil {
+			if err := os.RemoveAll(dstPath); err != nil {
+				return err
+			}
+			if err := os.Symlink(target, dstPath); err != nil {
+				return err
+			}
+		case entry.IsDir():
+			if err := c.copyDir(srcPath, dstPath); err != nil {
+				return err
+			}
+		case entry.Type().IsRegular():
+			if err := c.copyFile(srcPath, dstPath); err != nil {
 				return err
 			}
 		}
+		// Sockets, pipes and devices are skipped
 	}
 
-	return nil
+	// Set after the contents, which change the directory's mtime
+	return preserveAttributes(dst, srcInfo)
+}
+
+// preserveAttributes gives path the permissions and modification time of info
+func preserveAttributes(path string, info os.FileInfo) error {
+	if err := os.Chmod(path, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// replaceFile writes data to path through a new file, so that a hardlink
+// at path does not pass the change on to other names of the file
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".wt-tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	// WriteFile applies the umask
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
 }
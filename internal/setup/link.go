@@ -1,16 +1,28 @@
 package setup
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+
+	"github.com/superkoh/worktree-manager/internal/config"
 )
 
-// LinkPaths creates symbolic links from source to destination
+// LinkPaths creates symbolic links from source to destination for paths
+// matching the given entries. Links are absolute, or relative to their
+// directory when relative is set. Existing destinations are handled by the
+// entry policy.
 // On Windows, if symlink fails (requires admin/dev mode), it falls back to copy
-func LinkPaths(srcBase, dstBase string, paths []string, quiet bool) error {
-	for _, p := range paths {
+func LinkPaths(ctx context.Context, srcBase, dstBase string, entries []config.SetupEntry, relative, quiet bool) error {
+	matches, err := ExpandEntries(srcBase, entries, quiet)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		p := m.Path
 		src := filepath.Join(srcBase, p)
 		dst := filepath.Join(dstBase, p)
 
@@ -31,6 +43,14 @@ func LinkPaths(srcBase, dstBase string, paths []string, quiet bool) error {
 			return fmt.Errorf("failed to create directory for %s: %w", p, err)
 		}
 
+		ok, err := prepareDestination(ctx, dstBase, p, m.Entry, quiet)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
 		// Remove existing destination if it exists
 		if _, err := os.Lstat(dst); err == nil {
 			if err := os.RemoveAll(dst); err != nil {
@@ -39,7 +59,8 @@ func LinkPaths(srcBase, dstBase string, paths []string, quiet bool) error {
 		}
 
 		// Try to create symbolic link
-		if err := os.Symlink(src, dst); err != nil {
+		target := symlinkTarget(src, dst, relative)
+		if err := os.Symlink(target, dst); err != nil {
 			// On Windows, symlink may fail without admin privileges
 			// Fall back to copying
 			if runtime.GOOS == "windows" {
@@ -63,12 +84,25 @@ func LinkPaths(srcBase, dstBase string, paths []string, quiet bool) error {
 			return fmt.Errorf("failed to create symlink for %s: %w", p, err)
 		}
 		if !quiet {
-			fmt.Printf("  linked: %s -> %s\n", p, src)
+			fmt.Printf("  linked: %s -> %s\n", p, target)
 		}
 	}
 	return nil
 }
 
+// symlinkTarget returns the target of a link at dst pointing to src
+func symlinkTarget(src, dst string, relative bool) string {
+	if !relative {
+		return src
+	}
+	rel, err := filepath.Rel(filepath.Dir(dst), src)
+	if err != nil {
+		// Different volumes on Windows
+		return src
+	}
+	return rel
+}
+
 // IsSymlink checks if a path is a symbolic link
 func IsSymlink(path string) bool {
 	info, err := os.Lstat(path)
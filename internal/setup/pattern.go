@@ -0,0 +1,312 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+)
+
+// Match is a path matched by a setup entry
+type Match struct {
+	Path  string
+	Entry config.SetupEntry
+}
+
+// ExpandPatterns resolves setup entries against srcBase.
+//
+// Entries may be literal relative paths or glob patterns using *, ?, [...]
+// and ** (any number of directories). Entries starting with ! exclude
+// matching paths, and everything inside them, from the result. A matched
+// directory with excluded paths inside is replaced by its other entries.
+// Literal paths are returned even if they don't exist so callers can report
+// them. The result is deduplicated and paths nested inside an already
+// matched path are dropped.
+func ExpandPatterns(srcBase string, patterns []string, quiet bool) ([]string, error) {
+	matches, err := ExpandEntries(srcBase, config.Entries(patterns...), quiet)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(matches))
+	for i, m := range matches {
+		paths[i] = m.Path
+	}
+	return paths, nil
+}
+
+// ExpandEntries resolves setup entries like ExpandPatterns and returns each
+// path with the entry that matched it first
+func ExpandEntries(srcBase string, entries []config.SetupEntry, quiet bool) ([]Match, error) {
+	type include struct {
+		pattern string
+		entry   config.SetupEntry
+	}
+
+	var includes []include
+	var excludes []string
+	for _, e := range entries {
+		p := filepath.ToSlash(strings.TrimSpace(e.Path))
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "!") {
+			excludes = append(excludes, cleanPattern(p[1:]))
+		} else {
+			includes = append(includes, include{cleanPattern(p), e})
+		}
+	}
+
+	seen := make(map[string]bool)
+	var result []Match
+
+	for _, inc := range includes {
+		pattern := inc.pattern
+		var matches []string
+		if isGlob(pattern) {
+			var err error
+			matches, err = globPaths(srcBase, pattern, excludes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+			}
+		} else {
+			matches = []string{pattern}
+		}
+
+		count := 0
+		for _, m := range matches {
+			if seen[m] || isExcluded(m, excludes) || hasMatchedParent(m, seen) {
+				continue
+			}
+			for _, p := range withoutExcluded(srcBase, m, excludes) {
+				if !seen[p] {
+					seen[p] = true
+					result = append(result, Match{Path: p, Entry: inc.entry})
+				}
+			}
+			count++
+		}
+
+		if !quiet && isGlob(pattern) {
+			fmt.Printf("  pattern %s: %d match(es)\n", pattern, count)
+		}
+	}
+
+	return result, nil
+}
+
+// isGlob reports whether p contains glob metacharacters
+func isGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// cleanPattern normalizes a slash-separated pattern
+func cleanPattern(p string) string {
+	return strings.TrimPrefix(path.Clean(p), "./")
+}
+
+// isExcluded reports whether rel or one of its parent directories matches
+// any exclude pattern
+func isExcluded(rel string, excludes []string) bool {
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		for _, ex := range excludes {
+			if ok, _ := matchPattern(ex, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasExcludedInside reports whether an exclude pattern can match a path
+// inside the directory rel
+func hasExcludedInside(rel string, excludes []string) bool {
+	for _, ex := range excludes {
+		if matchesInside(strings.Split(ex, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesInside reports whether pattern can match a path below the
+// directory whose segments are parts
+func matchesInside(pattern, parts []string) bool {
+	for len(parts) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(pattern) > 0
+}
+
+// withoutExcluded returns rel, or when rel is a directory with excluded
+// paths inside, the entries of rel that are not excluded, split the same
+// way. Copies and links then leave out the excluded paths.
+func withoutExcluded(root, rel string, excludes []string) []string {
+	if !hasExcludedInside(rel, excludes) || !isRealDir(root, rel) {
+		return []string{rel}
+	}
+
+	var paths []string
+	for _, entry := range readDir(root, rel) {
+		child := path.Join(rel, entry.Name())
+		if !isExcluded(child, excludes) {
+			paths = append(paths, withoutExcluded(root, child, excludes)...)
+		}
+	}
+	return paths
+}
+
+// hasMatchedParent reports whether any ancestor of rel is already matched
+func hasMatchedParent(rel string, seen map[string]bool) bool {
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if seen[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// globPaths returns the slash-separated relative paths under root that
+// match pattern. Directories that are excluded or already matched are not
+// searched, and ** does not descend into another repository or worktree.
+func globPaths(root, pattern string, excludes []string) ([]string, error) {
+	// Validate each segment up front so bad patterns are reported
+	for _, seg := range strings.Split(pattern, "/") {
+		if seg != "**" {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	g := &globber{root: root, excludes: excludes, matched: make(map[string]bool)}
+	g.walk("", strings.Split(pattern, "/"))
+	sort.Strings(g.matches)
+	return g.matches, nil
+}
+
+// globber collects the paths matching a pattern
+type globber struct {
+	root     string
+	excludes []string
+	matches  []string
+	matched  map[string]bool
+}
+
+// walk matches the remaining pattern segments below rel
+func (g *globber) walk(rel string, segs []string) {
+	if len(segs) == 0 {
+		if rel != "" && !g.matched[rel] {
+			g.matched[rel] = true
+			g.matches = append(g.matches, rel)
+		}
+		return
+	}
+
+	seg := segs[0]
+
+	if seg == "**" {
+		// Zero directories
+		g.walk(rel, segs[1:])
+		// One or more directories
+		for _, entry := range readDir(g.root, rel) {
+			next := path.Join(rel, entry.Name())
+			if entry.IsDir() && g.searchable(next) && !isCheckout(g.root, next) {
+				g.walk(next, segs)
+			}
+		}
+		return
+	}
+
+	if !isGlob(seg) {
+		next := path.Join(rel, seg)
+		if _, err := os.Lstat(filepath.Join(g.root, filepath.FromSlash(next))); err == nil {
+			if len(segs) == 1 || (isDir(g.root, next) && g.searchable(next)) {
+				g.walk(next, segs[1:])
+			}
+		}
+		return
+	}
+
+	for _, entry := range readDir(g.root, rel) {
+		if entry.Name() == ".git" {
+			continue
+		}
+		if ok, _ := path.Match(seg, entry.Name()); !ok {
+			continue
+		}
+		next := path.Join(rel, entry.Name())
+		if len(segs) == 1 || (entry.IsDir() && g.searchable(next)) {
+			g.walk(next, segs[1:])
+		}
+	}
+}
+
+// searchable reports whether the directory rel may hold further matches.
+// Matches inside an excluded or matched directory would be dropped.
+func (g *globber) searchable(rel string) bool {
+	return path.Base(rel) != ".git" && !g.matched[rel] && !isExcluded(rel, g.excludes)
+}
+
+// isCheckout reports whether the directory rel is the top of a nested
+// repository, submodule or worktree
+func isCheckout(root, rel string) bool {
+	_, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel), ".git"))
+	return err == nil
+}
+
+// matchPattern reports whether the slash-separated path rel matches pattern
+func matchPattern(pattern, rel string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if ok, err := matchSegments(pattern[1:], parts[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(parts) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], parts[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0, nil
+}
+
+func readDir(root, rel string) []os.DirEntry {
+	entries, _ := os.ReadDir(filepath.Join(root, filepath.FromSlash(rel)))
+	return entries
+}
+
+// isRealDir reports whether rel is a directory, not a symlink to one
+func isRealDir(root, rel string) bool {
+	info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel)))
+	return err == nil && info.IsDir()
+}
+
+func isDir(root, rel string) bool {
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel)))
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,71 @@
+package setup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeTree creates the given files, with parent directories, under root
+func makeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandPatternsExcludesInsideMatches(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root,
+		"config/app.json",
+		"config/secrets/key.pem",
+		"config/local/db.json",
+		"config/local/secret.txt",
+	)
+
+	got, err := ExpandPatterns(root, []string{"config", "!config/secrets", "!**/secret.*"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"config/app.json", "config/local/db.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExpandPatternsPrunesWalk(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root,
+		".env",
+		"app/.env",
+		"node_modules/pkg/.env",
+		"node_modules/pkg/node_modules/dep/.env",
+		// A worktree placed inside the repository
+		".worktrees/feature/.git",
+		".worktrees/feature/.env",
+	)
+
+	got, err := ExpandPatterns(root, []string{"**/.env", "!node_modules"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".env", "app/.env"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Matched directories are not searched for nested matches
+	got, err = ExpandPatterns(root, []string{"**/node_modules"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"node_modules"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package setup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+)
+
+// RecordFileName is the setup record inside the git directory of a worktree
+const RecordFileName = "wt-setup.json"
+
+// Record remembers what setup wrote into a worktree, so that local edits
+// can be told apart from stale files later
+type Record struct {
+	// Copies maps copied files to the checksum of the content written
+	Copies map[string]string `json:"copies,omitempty"`
+	// Templates maps rendered files to the checksum of the content written
+	Templates map[string]string `json:"templates,omitempty"`
+
+	path string
+}
+
+// LoadRecord reads the setup record of the worktree at path.
+// A missing record is empty.
+func LoadRecord(worktree string) (*Record, error) {
+	gitDir, err := git.WorktreeGitDir(worktree)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Record{path: filepath.Join(gitDir, RecordFileName)}
+	data, err := os.ReadFile(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, fmt.Errorf("invalid setup record %s: %w", r.path, err)
+		}
+	}
+
+	if r.Copies == nil {
+		r.Copies = make(map[string]string)
+	}
+	if r.Templates == nil {
+		r.Templates = make(map[string]string)
+	}
+	return r, nil
+}
+
+// recordCopies records the checksums of the files copied into dstBase for
+// the given entries. Files that differ from their source, because they
+// were preserved or merged, are not recorded; a copy keeps the size and
+// modification time of its source, so the source is not read to tell.
+// Hardlinked and cloned directories are not recorded, see sharesContents.
+func (r *Record) recordCopies(srcBase, dstBase string, entries []config.SetupEntry) error {
+	matches, err := ExpandEntries(srcBase, entries, true)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		if sharesContents(srcBase, m) {
+			continue
+		}
+		err := walkFiles(dstBase, m.Path, func(rel string) error {
+			dst := filepath.Join(dstBase, rel)
+			if !sameSizeAndTime(filepath.Join(srcBase, rel), dst) {
+				return nil
+			}
+			sum, err := FileChecksum(dst)
+			if err != nil {
+				return err
+			}
+			r.Copies[rel] = sum
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharesContents reports whether m is a directory created as hardlinks or
+// reflinks. Such directories, often dependency trees, share their contents
+// with the source and are too large to hash, so sync only adds files
+// missing in them.
+func sharesContents(srcBase string, m Match) bool {
+	if m.Entry.Mode != config.ModeHardlink && m.Entry.Mode != config.ModeReflink {
+		return false
+	}
+	return isDir(srcBase, m.Path)
+}
+
+// sameSizeAndTime reports whether the files at a and b have the same size
+// and modification time
+func sameSizeAndTime(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return ai.Size() == bi.Size() && ai.ModTime().Equal(bi.ModTime())
+}
+
+// Save writes the record
+func (r *Record) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+// ModifiedTemplates returns the rendered files under dstBase whose content
+// no longer matches the checksum recorded when they were written.
+// Deleted files are reported as modified too.
+func (r *Record) ModifiedTemplates(dstBase string) []string {
+	var modified []string
+	for p, sum := range r.Templates {
+		current, err := FileChecksum(filepath.Join(dstBase, p))
+		if err != nil || current != sum {
+			modified = append(modified, p)
+		}
+	}
+	sort.Strings(modified)
+	return modified
+}
+
+// Checksum returns the hex encoded SHA-256 of data
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileChecksum returns the checksum of the file at path
+func FileChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return Checksum(data), nil
+}
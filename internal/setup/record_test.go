@@ -0,0 +1,44 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+)
+
+func TestRecordCopies(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	makeTree(t, src, "conf/app.json", "deps/pkg/index.js", ".env")
+	// An existing file kept by the skip policy differs from its source
+	if err := os.WriteFile(filepath.Join(dst, ".env"), []byte("LOCAL=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []config.SetupEntry{
+		{Path: "conf", Mode: config.ModeCopy},
+		{Path: "deps", Mode: config.ModeHardlink},
+		{Path: ".env", Mode: config.ModeCopy, Policy: config.PolicySkip, AllowTracked: true},
+	}
+	if err := CopyFiles(context.Background(), src, dst, entries, true); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Record{Copies: make(map[string]string)}
+	if err := r.recordCopies(src, dst, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	var recorded []string
+	for p := range r.Copies {
+		recorded = append(recorded, p)
+	}
+	sort.Strings(recorded)
+	if want := []string{"conf/app.json"}; !reflect.DeepEqual(recorded, want) {
+		t.Errorf("recorded %v, want %v", recorded, want)
+	}
+}
@@ -0,0 +1,119 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RetargetLinks rewrites symlinks created by LinkPaths in dstBase whose
+// target lies inside oldBase so that they point at the same location
+// under newBase. When dstBase itself is the moved directory, relative links
+// are also rewritten so that they keep pointing at the same target.
+// It returns the number of links that were rewritten.
+func RetargetLinks(dstBase string, patterns []string, oldBase, newBase string, quiet bool) (int, error) {
+	paths, err := ExpandPatterns(dstBase, patterns, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, p := range paths {
+		dst := filepath.Join(dstBase, p)
+		if !IsSymlink(dst) {
+			continue
+		}
+
+		target, err := ReadSymlink(dst)
+		if err != nil {
+			return count, fmt.Errorf("failed to read link %s: %w", p, err)
+		}
+
+		// Resolve relative targets from where the link was created
+		abs := target
+		if !filepath.IsAbs(target) {
+			dir := filepath.Dir(dst)
+			if dstBase == newBase {
+				dir = filepath.Dir(filepath.Join(oldBase, p))
+			}
+			abs = filepath.Join(dir, target)
+		}
+		if rel, ok := relativeTo(oldBase, abs); ok {
+			abs = filepath.Join(newBase, rel)
+		}
+
+		newTarget := symlinkTarget(abs, dst, !filepath.IsAbs(target))
+		if newTarget == target {
+			continue
+		}
+
+		if err := replaceLink(dst, newTarget); err != nil {
+			return count, fmt.Errorf("failed to relink %s: %w", p, err)
+		}
+		if !quiet {
+			fmt.Printf("  relinked: %s -> %s\n", dst, newTarget)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// ConvertLinks rewrites the symlinks in dstBase that LinkPaths created for
+// the given patterns, those pointing at the same path in srcBase, to be
+// relative or absolute. It returns the number of links that were rewritten.
+func ConvertLinks(srcBase, dstBase string, patterns []string, relative, quiet bool) (int, error) {
+	paths, err := ExpandPatterns(srcBase, patterns, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, p := range paths {
+		src := filepath.Join(srcBase, p)
+		dst := filepath.Join(dstBase, p)
+		if !IsSymlink(dst) || linkTarget(dst) != filepath.Clean(src) {
+			continue
+		}
+
+		target, err := ReadSymlink(dst)
+		if err != nil {
+			return count, fmt.Errorf("failed to read link %s: %w", p, err)
+		}
+		newTarget := symlinkTarget(src, dst, relative)
+		if newTarget == target {
+			continue
+		}
+
+		if err := replaceLink(dst, newTarget); err != nil {
+			return count, fmt.Errorf("failed to relink %s: %w", p, err)
+		}
+		if !quiet {
+			fmt.Printf("  relinked: %s -> %s\n", p, newTarget)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// replaceLink points the symlink at dst to target
+func replaceLink(dst, target string) error {
+	if err := os.Remove(dst); err != nil {
+		return err
+	}
+	return os.Symlink(target, dst)
+}
+
+// relativeTo returns target relative to base if target is base or lies inside it
+func relativeTo(base, target string) (string, bool) {
+	if !filepath.IsAbs(target) {
+		return "", false
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -1,29 +1,62 @@
 package setup
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/superkoh/worktree-manager/internal/config"
 )
 
-// RunSetup performs the copy and link operations for a new worktree
-func RunSetup(cfg *config.Config, srcDir, dstDir string, quiet bool) error {
+// RunSetup performs the copy, template and link operations for a new
+// worktree. Templates are rendered with data. The checksums of copied and
+// rendered files are kept in the worktree's setup record for wt sync.
+func RunSetup(ctx context.Context, cfg *config.Config, srcDir, dstDir string, data *TemplateData, quiet bool) error {
+	record, err := LoadRecord(dstDir)
+	if err != nil {
+		return fmt.Errorf("failed to load setup record: %w", err)
+	}
+	copies := cfg.Setup.CopyEntries()
+	links := cfg.Setup.LinkEntries()
+
 	// Copy files
-	if len(cfg.Setup.Copy) > 0 {
+	if len(copies) > 0 {
 		if !quiet {
 			fmt.Println("Copying files...")
 		}
-		if err := CopyFiles(srcDir, dstDir, cfg.Setup.Copy, quiet); err != nil {
+		if err := CopyFiles(ctx, srcDir, dstDir, copies, quiet); err != nil {
 			return fmt.Errorf("copy failed: %w", err)
 		}
+		if err := record.recordCopies(srcDir, dstDir, copies); err != nil {
+			return fmt.Errorf("failed to record copies: %w", err)
+		}
+	}
+
+	// Render templates
+	if len(cfg.Setup.Template) > 0 {
+		if !quiet {
+			fmt.Println("Rendering templates...")
+		}
+		checksums, err := RenderTemplates(ctx, srcDir, dstDir, cfg.Setup.Template, data, quiet)
+		if err != nil {
+			return fmt.Errorf("template failed: %w", err)
+		}
+		for p, sum := range checksums {
+			record.Templates[p] = sum
+		}
+	}
+
+	if len(record.Copies) > 0 || len(record.Templates) > 0 {
+		if err := record.Save(); err != nil {
+			return fmt.Errorf("failed to save setup record: %w", err)
+		}
 	}
 
 	// Create symlinks
-	if len(cfg.Setup.Link) > 0 {
+	if len(links) > 0 {
 		if !quiet {
 			fmt.Println("Creating symlinks...")
 		}
-		if err := LinkPaths(srcDir, dstDir, cfg.Setup.Link, quiet); err != nil {
+		if err := LinkPaths(ctx, srcDir, dstDir, links, cfg.Setup.RelativeLinks, quiet); err != nil {
 			return fmt.Errorf("link failed: %w", err)
 		}
 	}
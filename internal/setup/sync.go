@@ -0,0 +1,359 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+	"github.com/superkoh/worktree-manager/internal/git"
+)
+
+// Kinds of setup entries
+const (
+	KindCopy     = "copy"
+	KindTemplate = "template"
+	KindLink     = "link"
+)
+
+// States of a setup entry that differs from its source
+const (
+	SyncMissing     = "missing"
+	SyncStale       = "stale"
+	SyncModified    = "modified"
+	SyncWrongTarget = "wrong target"
+	SyncNotLink     = "not a link"
+	SyncTracked     = "tracked"
+)
+
+// SyncItem is a setup entry of a worktree that differs from what setup
+// would produce from the source
+type SyncItem struct {
+	Kind  string
+	Path  string
+	State string
+	// Blocked is set when applying the item would discard local changes
+	Blocked bool
+	// Protected is set for paths tracked by git, which are never replaced
+	Protected bool
+
+	src      string
+	rendered []byte
+	entry    config.SetupEntry
+	relative bool
+}
+
+// PlanSync compares dstDir with what RunSetup would produce from srcDir and
+// returns the entries that differ. Hardlinked and cloned directories are
+// only checked for missing files. A copy or rendered template whose content
+// still matches the setup record is stale and safe to update; one changed
+// since setup, or never recorded, is modified and blocked. Entry policies
+// are respected: existing files of skip and fail entries are left alone,
+// merge-env entries only add missing variables, and paths tracked by git
+// are protected unless the entry allows them.
+func PlanSync(ctx context.Context, cfg *config.Config, srcDir, dstDir string, data *TemplateData) ([]SyncItem, error) {
+	record, err := LoadRecord(dstDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []SyncItem
+
+	// Copies are compared file by file
+	copies, err := ExpandEntries(srcDir, cfg.Setup.CopyEntries(), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range copies {
+		shared := sharesContents(srcDir, m)
+		err := walkFiles(srcDir, m.Path, func(rel string) error {
+			src := filepath.Join(srcDir, rel)
+			dst := filepath.Join(dstDir, rel)
+			if shared && exists(dst) {
+				return nil
+			}
+			content, err := os.ReadFile(src)
+			if err != nil {
+				return err
+			}
+
+			item := SyncItem{Kind: KindCopy, Path: rel, src: src, entry: m.Entry}
+			item.State, item.Blocked = compareFile(dst, content, record.Copies[rel])
+
+			switch {
+			case item.State == "" || item.State == SyncMissing:
+			case m.Entry.Policy == config.PolicySkip, m.Entry.Policy == config.PolicyFail:
+				return nil
+			case m.Entry.Policy == config.PolicyMergeEnv && isRegularFile(dst):
+				current, err := os.ReadFile(dst)
+				if err != nil {
+					return err
+				}
+				merged, added := mergeEnv(current, content)
+				if len(added) == 0 {
+					return nil
+				}
+				item.State, item.Blocked, item.rendered = SyncStale, false, merged
+			}
+
+			if item.State != "" {
+				if err := protectTracked(ctx, dstDir, &item); err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %w", m.Path, err)
+		}
+	}
+
+	// Templates are rendered and compared with their output
+	templates, err := ExpandPatterns(srcDir, cfg.Setup.Template, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range templates {
+		src := filepath.Join(srcDir, p)
+		if info, err := os.Stat(src); err != nil || info.IsDir() {
+			continue
+		}
+		content, err := renderTemplate(src, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", p, err)
+		}
+		out := TemplateOutput(p)
+		state, blocked := compareFile(filepath.Join(dstDir, out), content, record.Templates[out])
+		if state != "" {
+			item := SyncItem{Kind: KindTemplate, Path: out, State: state, Blocked: blocked, src: src, rendered: content}
+			if err := protectTracked(ctx, dstDir, &item); err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+
+	// Links must point at the source
+	links, err := ExpandEntries(srcDir, cfg.Setup.LinkEntries(), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range links {
+		p := m.Path
+		src := filepath.Join(srcDir, p)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		dst := filepath.Join(dstDir, p)
+		item := SyncItem{Kind: KindLink, Path: p, src: src, entry: m.Entry, relative: cfg.Setup.RelativeLinks}
+		switch {
+		case !exists(dst):
+			item.State = SyncMissing
+		case m.Entry.Policy == config.PolicySkip, m.Entry.Policy == config.PolicyFail:
+			continue
+		case IsSymlink(dst):
+			if linkTarget(dst) == filepath.Clean(src) {
+				continue
+			}
+			item.State = SyncWrongTarget
+		case runtime.GOOS == "windows":
+			// Without symlink support, LinkPaths falls back to copying
+			continue
+		default:
+			item.State = SyncNotLink
+			item.Blocked = true
+		}
+		if err := protectTracked(ctx, dstDir, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// protectTracked marks an item whose existing destination is tracked by git
+func protectTracked(ctx context.Context, dstDir string, item *SyncItem) error {
+	if item.State == SyncMissing || item.entry.AllowTracked {
+		return nil
+	}
+	tracked, err := git.IsTracked(ctx, dstDir, item.Path)
+	if err != nil {
+		return err
+	}
+	if tracked {
+		item.State = SyncTracked
+		item.Blocked = false
+		item.Protected = true
+	}
+	return nil
+}
+
+// ApplySync applies the items of a sync plan to dstDir. Blocked items are
+// only applied when force is set, protected ones never. Existing files are
+// backed up first for entries with the backup policy. The setup record is
+// updated with what was written, and the number of applied items is returned.
+func ApplySync(ctx context.Context, srcDir, dstDir string, items []SyncItem, force, quiet bool) (int, error) {
+	record, err := LoadRecord(dstDir)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, item := range items {
+		if item.Protected || (item.Blocked && !force) {
+			continue
+		}
+
+		dst := filepath.Join(dstDir, item.Path)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return applied, fmt.Errorf("failed to create directory for %s: %w", item.Path, err)
+		}
+		if item.entry.Policy == config.PolicyBackup && item.Kind != KindLink && exists(dst) {
+			backup, err := backupPath(dst)
+			if err != nil {
+				return applied, err
+			}
+			if err := os.Rename(dst, backup); err != nil {
+				return applied, fmt.Errorf("failed to back up %s: %w", item.Path, err)
+			}
+			if !quiet {
+				fmt.Printf("  backed up: %s -> %s\n", item.Path, filepath.Base(backup))
+			}
+		}
+		if item.Blocked && item.Kind != KindLink {
+			// A directory or symlink in the way of a file
+			if info, err := os.Lstat(dst); err == nil && !info.Mode().IsRegular() {
+				if err := os.RemoveAll(dst); err != nil {
+					return applied, fmt.Errorf("failed to remove %s: %w", item.Path, err)
+				}
+			}
+		}
+
+		switch {
+		case item.Kind == KindCopy && item.rendered != nil:
+			// Merged dotenv file, which stays different from its source
+			info, err := os.Lstat(dst)
+			if err != nil {
+				return applied, err
+			}
+			if err := replaceFile(dst, item.rendered, info.Mode().Perm()); err != nil {
+				return applied, fmt.Errorf("failed to merge %s: %w", item.Path, err)
+			}
+		case item.Kind == KindCopy:
+			c := &copier{mode: item.entry.Mode}
+			if err := c.copyFile(item.src, dst); err != nil {
+				return applied, fmt.Errorf("failed to copy file %s: %w", item.Path, err)
+			}
+			sum, err := FileChecksum(dst)
+			if err != nil {
+				return applied, err
+			}
+			record.Copies[item.Path] = sum
+		case item.Kind == KindTemplate:
+			info, err := os.Stat(item.src)
+			if err != nil {
+				return applied, err
+			}
+			if err := os.WriteFile(dst, item.rendered, info.Mode().Perm()); err != nil {
+				return applied, fmt.Errorf("failed to write %s: %w", item.Path, err)
+			}
+			record.Templates[item.Path] = Checksum(item.rendered)
+		case item.Kind == KindLink:
+			entry := item.entry
+			entry.Path = item.Path
+			if err := LinkPaths(ctx, srcDir, dstDir, []config.SetupEntry{entry}, item.relative, true); err != nil {
+				return applied, err
+			}
+		}
+
+		if !quiet {
+			fmt.Printf("  %s (%s): %s\n", item.Kind, item.State, item.Path)
+		}
+		applied++
+	}
+
+	if err := record.Save(); err != nil {
+		return applied, fmt.Errorf("failed to save setup record: %w", err)
+	}
+	return applied, nil
+}
+
+// compareFile compares the file at dst with the content setup would write.
+// recorded is the checksum of what setup last wrote there, if known.
+// It returns an empty state when the file is up to date.
+func compareFile(dst string, content []byte, recorded string) (state string, blocked bool) {
+	info, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return SyncMissing, false
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		return SyncModified, true
+	}
+
+	current, err := FileChecksum(dst)
+	if err != nil {
+		return SyncModified, true
+	}
+	if current == Checksum(content) {
+		return "", false
+	}
+	if current == recorded {
+		return SyncStale, false
+	}
+	return SyncModified, true
+}
+
+// walkFiles calls fn with the slash-separated relative path of every
+// regular file at or below rel in base
+func walkFiles(base, rel string, fn func(rel string) error) error {
+	root := filepath.Join(base, rel)
+	info, err := os.Stat(root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fn(rel)
+	}
+
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		sub, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		return fn(path.Join(rel, filepath.ToSlash(sub)))
+	})
+}
+
+// linkTarget returns the absolute, cleaned target of the symlink at link
+func linkTarget(link string) string {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	return filepath.Clean(target)
+}
+
+// exists reports whether anything, including a dangling symlink, is at p
+func exists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
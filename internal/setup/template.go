@@ -0,0 +1,157 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/superkoh/worktree-manager/internal/config"
+)
+
+// TemplateSuffix is stripped from the names of rendered template files,
+// so that .env.tmpl is written as .env
+const TemplateSuffix = ".tmpl"
+
+// TemplateData is the data available to files listed in setup.template
+type TemplateData struct {
+	Branch       string
+	Repo         string
+	WorktreeName string
+	Path         string
+	MainWorktree string
+	Ports        map[string]int
+	Env          map[string]string
+}
+
+// NewTemplateData returns the template data of the worktree at path
+func NewTemplateData(branch, repo, path, mainWorktree string, ports map[string]int) *TemplateData {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return &TemplateData{
+		Branch:       branch,
+		Repo:         repo,
+		WorktreeName: filepath.Base(path),
+		Path:         path,
+		MainWorktree: mainWorktree,
+		Ports:        ports,
+		Env:          env,
+	}
+}
+
+// Port returns the port allocated to the worktree under name.
+// Rendering fails if the port is not declared in resources.ports.
+func (d *TemplateData) Port(name string) (int, error) {
+	port, ok := d.Ports[name]
+	if !ok {
+		declared := make([]string, 0, len(d.Ports))
+		for n := range d.Ports {
+			declared = append(declared, n)
+		}
+		sort.Strings(declared)
+		return 0, fmt.Errorf("port %q is not allocated (declared: %s)", name, strings.Join(declared, ", "))
+	}
+	return port, nil
+}
+
+// TemplateOutput returns where the template at the relative path p is written
+func TemplateOutput(p string) string {
+	if filepath.Base(p) == TemplateSuffix {
+		return p
+	}
+	return strings.TrimSuffix(p, TemplateSuffix)
+}
+
+// RenderTemplates renders the files matching the given patterns from source
+// into destination with text/template. Existing outputs are handled like
+// setup.copy entries with the default policy: paths tracked by git are kept
+// and symlinks are replaced rather than written through. It returns the
+// SHA-256 checksum of each written file by its relative output path.
+func RenderTemplates(ctx context.Context, srcBase, dstBase string, patterns []string, data *TemplateData, quiet bool) (map[string]string, error) {
+	paths, err := ExpandPatterns(srcBase, patterns, quiet)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(paths))
+	for _, p := range paths {
+		src := filepath.Join(srcBase, p)
+		out := TemplateOutput(p)
+
+		info, err := os.Stat(src)
+		if os.IsNotExist(err) {
+			if !quiet {
+				fmt.Printf("  skip (not found): %s\n", p)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", p, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("template %s is a directory", p)
+		}
+
+		content, err := renderTemplate(src, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", p, err)
+		}
+
+		ok, err := prepareDestination(ctx, dstBase, out, config.SetupEntry{Path: out}, quiet)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		dst := filepath.Join(dstBase, out)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", out, err)
+		}
+		if err := os.WriteFile(dst, content, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", out, err)
+		}
+
+		checksums[out] = Checksum(content)
+		if !quiet {
+			if out != p {
+				fmt.Printf("  rendered: %s -> %s\n", p, out)
+			} else {
+				fmt.Printf("  rendered: %s\n", p)
+			}
+		}
+	}
+	return checksums, nil
+}
+
+// renderTemplate executes the template file at path with data
+func renderTemplate(path string, data *TemplateData) ([]byte, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{"env": os.Getenv}).
+		Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
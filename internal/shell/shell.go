@@ -0,0 +1,139 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CdFileEnv names the environment variable set by the shell wrapper. It
+// holds the path of a file that wt writes the directory to change to into.
+const CdFileEnv = "WT_CD_FILE"
+
+// RequestCd asks the shell wrapper to change to dir once wt exits.
+// It reports false when wt is not running under the shell wrapper.
+func RequestCd(dir string) (bool, error) {
+	path := os.Getenv(CdFileEnv)
+	if path == "" {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(dir+"\n"), 0600); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", CdFileEnv, err)
+	}
+	return true, nil
+}
+
+// Script returns the wrapper function for the given shell
+func Script(name string) (string, error) {
+	script, ok := scripts[name]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", name, strings.Join(Names(), ", "))
+	}
+	return script, nil
+}
+
+// Names returns the supported shells
+func Names() []string {
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var scripts = map[string]string{
+	"bash":       posixScript,
+	"zsh":        posixScript,
+	"fish":       fishScript,
+	"powershell": powershellScript,
+	"nushell":    nushellScript,
+}
+
+const posixScript = `# wt shell integration
+# Add to your shell profile: eval "$(wt shell-init bash)"
+wt() {
+    local __wt_tmp __wt_code __wt_dir
+    __wt_tmp=$(mktemp "${TMPDIR:-/tmp}/wt.XXXXXX") || { command wt "$@"; return; }
+    WT_CD_FILE="$__wt_tmp" command wt "$@"
+    __wt_code=$?
+    __wt_dir=$(cat "$__wt_tmp" 2>/dev/null)
+    rm -f "$__wt_tmp"
+    if [ -n "$__wt_dir" ] && [ -d "$__wt_dir" ]; then
+        cd "$__wt_dir" || return
+    fi
+    return $__wt_code
+}
+`
+
+const fishScript = `# wt shell integration
+# Add to ~/.config/fish/config.fish: wt shell-init fish | source
+function wt
+    set -l tmp (mktemp -t wt.XXXXXX)
+    or begin
+        command wt $argv
+        return
+    end
+    WT_CD_FILE=$tmp command wt $argv
+    set -l code $status
+    set -l dir (cat $tmp 2>/dev/null)
+    rm -f $tmp
+    if test -n "$dir"; and test -d "$dir"
+        cd $dir
+    end
+    return $code
+end
+`
+
+const powershellScript = `# wt shell integration
+# Add to your profile: Invoke-Expression (& wt shell-init powershell | Out-String)
+function Invoke-Wt {
+    $wtExe = (Get-Command wt -CommandType Application | Select-Object -First 1).Source
+    $tmp = New-TemporaryFile
+    $env:WT_CD_FILE = $tmp.FullName
+    try {
+        & $wtExe @args
+        $code = $LASTEXITCODE
+    }
+    finally {
+        Remove-Item Env:WT_CD_FILE -ErrorAction SilentlyContinue
+    }
+    $dir = Get-Content $tmp.FullName -Raw -ErrorAction SilentlyContinue
+    Remove-Item $tmp.FullName -Force -ErrorAction SilentlyContinue
+    if ($dir) {
+        $dir = $dir.Trim()
+        if (Test-Path $dir -PathType Container) {
+            Set-Location $dir
+        }
+    }
+    $global:LASTEXITCODE = $code
+}
+
+Set-Alias -Name wt -Value Invoke-Wt -Scope Global -Force
+`
+
+const nushellScript = `# wt shell integration
+# Save the output and source it from config.nu:
+#   wt shell-init nushell | save -f ~/.config/nushell/wt.nu
+#   source ~/.config/nushell/wt.nu
+def --env --wrapped wt [...args] {
+    let tmp = (mktemp -t wt.XXXXXX)
+    # A failing external command raises an error; catch it so the file is
+    # always removed, and raise it again once done
+    let code = try {
+        with-env { WT_CD_FILE: $tmp } { ^wt ...$args }
+        0
+    } catch {
+        $env.LAST_EXIT_CODE
+    }
+    let dir = (open --raw $tmp | str trim)
+    rm -f $tmp
+    if ($dir | is-not-empty) and ($dir | path exists) {
+        cd $dir
+    }
+    if $code != 0 {
+        error make --unspanned { msg: $"wt exited with status ($code)" }
+    }
+}
+`
@@ -0,0 +1,173 @@
+package tui
+
+import (
+	"math"
+	"sort"
+	"unicode"
+)
+
+// Fuzzy scoring weights
+const (
+	scoreMatch        = 16
+	bonusFirstChar    = 10
+	bonusBoundary     = 8
+	bonusConsecutive  = 12
+	bonusCase         = 1
+	penaltyGapStart   = 3
+	penaltyGapExtend  = 1
+	penaltyOtherField = 20
+)
+
+const noScore = math.MinInt / 2
+
+// fuzzyResult is the best match of a query against an item
+type fuzzyResult struct {
+	score   int
+	namePos []int // matched rune indexes in Name
+	descPos []int // matched rune indexes in Description
+}
+
+// fuzzyFilter returns the items matching query, best matches first.
+// Name, Description and Path are searched; matches outside the name rank
+// lower. The match positions are returned by item key for highlighting.
+func fuzzyFilter(items []Item, query string) ([]Item, map[string]fuzzyResult) {
+	type scored struct {
+		item   Item
+		result fuzzyResult
+	}
+
+	var matches []scored
+	for _, item := range items {
+		best := fuzzyResult{score: noScore}
+		if score, pos, ok := fuzzyScore(item.Name, query); ok {
+			best = fuzzyResult{score: score, namePos: pos}
+		}
+		if score, pos, ok := fuzzyScore(item.Description, query); ok && score-penaltyOtherField > best.score {
+			best = fuzzyResult{score: score - penaltyOtherField, descPos: pos}
+		}
+		if score, _, ok := fuzzyScore(item.Path, query); ok && score-penaltyOtherField > best.score {
+			best = fuzzyResult{score: score - penaltyOtherField}
+		}
+		if best.score != noScore {
+			matches = append(matches, scored{item, best})
+		}
+	}
+
+	// Keep the input order for equal scores
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].result.score > matches[j].result.score
+	})
+
+	result := make([]Item, len(matches))
+	positions := make(map[string]fuzzyResult, len(matches))
+	for i, m := range matches {
+		result[i] = m.item
+		positions[m.item.key()] = m.result
+	}
+	return result, positions
+}
+
+// fuzzyScore finds the best alignment of pattern as a subsequence of text.
+// Characters score more at the start of the text and of words (after /, -,
+// _, . or a space, or at a camelCase hump), in runs of consecutive
+// characters and when their case matches; gaps between them cost points.
+// It returns the score and matched rune indexes, or ok=false.
+func fuzzyScore(text, pattern string) (score int, positions []int, ok bool) {
+	t := []rune(text)
+	p := []rune(pattern)
+	n, m := len(t), len(p)
+	if m == 0 {
+		return 0, nil, true
+	}
+	if m > n {
+		return 0, nil, false
+	}
+
+	bonus := make([]int, n)
+	for i := range t {
+		switch {
+		case i == 0:
+			bonus[i] = bonusFirstChar
+		case isWordSeparator(t[i-1]),
+			unicode.IsLower(t[i-1]) && unicode.IsUpper(t[i]):
+			bonus[i] = bonusBoundary
+		}
+	}
+
+	// scores[j][i] is the best score with p[j] matched at t[i];
+	// from[j][i] is where p[j-1] was matched in that alignment
+	scores := make([][]int, m)
+	from := make([][]int, m)
+	for j := range scores {
+		scores[j] = make([]int, n)
+		from[j] = make([]int, n)
+		for i := range scores[j] {
+			scores[j][i] = noScore
+		}
+	}
+
+	for j := 0; j < m; j++ {
+		// Best previous match followed by a gap, decayed as the gap grows
+		gapScore, gapFrom := noScore, -1
+
+		for i := j; i < n; i++ {
+			if j > 0 && i >= 2 {
+				gapScore -= penaltyGapExtend
+				if s := scores[j-1][i-2] - penaltyGapStart; s > gapScore {
+					gapScore, gapFrom = s, i-2
+				}
+			}
+
+			if unicode.ToLower(t[i]) != unicode.ToLower(p[j]) {
+				continue
+			}
+
+			char := scoreMatch + bonus[i]
+			if t[i] == p[j] {
+				char += bonusCase
+			}
+
+			if j == 0 {
+				scores[j][i] = char
+				continue
+			}
+
+			prev, prevFrom := gapScore, gapFrom
+			if s := scores[j-1][i-1]; s != noScore && s+bonusConsecutive > prev {
+				prev, prevFrom = s+bonusConsecutive, i-1
+			}
+			if prev <= noScore/2 {
+				continue
+			}
+			scores[j][i] = prev + char
+			from[j][i] = prevFrom
+		}
+	}
+
+	end := -1
+	score = noScore
+	for i := m - 1; i < n; i++ {
+		if scores[m-1][i] > score {
+			score, end = scores[m-1][i], i
+		}
+	}
+	if end < 0 || score <= noScore/2 {
+		return 0, nil, false
+	}
+
+	positions = make([]int, m)
+	for j := m - 1; j >= 0; j-- {
+		positions[j] = end
+		end = from[j][end]
+	}
+	return score, positions, true
+}
+
+// isWordSeparator reports whether r separates words in branch names and paths
+func isWordSeparator(r rune) bool {
+	switch r {
+	case '/', '-', '_', '.', ' ':
+		return true
+	}
+	return false
+}
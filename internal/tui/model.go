@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -15,6 +16,22 @@ type Item struct {
 	Path        string
 	Description string
 	IsCurrent   bool
+	IsDirty     bool
+}
+
+// key identifies an item across filtering
+func (i Item) key() string {
+	return i.Name + "\x00" + i.Path
+}
+
+// PreviewFunc renders the preview of an item. It is called outside the
+// UI loop, so it may run slow commands; ctx ends when the selector closes.
+type PreviewFunc func(ctx context.Context, item Item) string
+
+// previewMsg carries a rendered preview back to the UI loop
+type previewMsg struct {
+	key     string
+	content string
 }
 
 // Model is the Bubbletea model for selection
@@ -22,11 +39,27 @@ type Model struct {
 	title     string
 	items     []Item
 	filtered  []Item
+	matches   map[string]fuzzyResult
 	cursor    int
 	textInput textinput.Model
 	selected  *Item
 	quitting  bool
 	filtering bool
+
+	// Multi-select mode
+	multi         bool
+	checked       map[string]bool
+	confirming    bool
+	confirmPrompt string
+	confirmed     []Item
+
+	// Preview pane, loaded lazily per item
+	preview     PreviewFunc
+	previewCtx  context.Context
+	showPreview bool
+	previews    map[string]string
+	loading     map[string]bool
+	width       int
 }
 
 // Styles
@@ -57,6 +90,27 @@ var (
 
 	filterStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("205"))
+
+	matchStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("212")).
+			Underline(true)
+
+	checkedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("42"))
+
+	dirtyStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("203"))
+
+	previewStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240")).
+			Padding(0, 1)
+)
+
+// Preview pane limits
+const (
+	minSplitWidth   = 100
+	maxPreviewLines = 20
 )
 
 // NewModel creates a new TUI model
@@ -76,23 +130,86 @@ func NewModel(title string, items []Item) Model {
 	}
 }
 
+// NewMultiModel creates a TUI model that selects several items and asks
+// for confirmation with prompt before returning them
+func NewMultiModel(title string, items []Item, prompt string) Model {
+	m := NewModel(title, items)
+	m.multi = true
+	m.checked = make(map[string]bool)
+	m.confirmPrompt = prompt
+	return m
+}
+
+// NewPreviewModel creates a TUI model that shows a preview of the
+// highlighted item next to the list
+func NewPreviewModel(title string, items []Item, preview PreviewFunc) Model {
+	m := NewModel(title, items)
+	m.preview = preview
+	m.previewCtx = context.Background()
+	m.showPreview = preview != nil
+	m.previews = make(map[string]string)
+	m.loading = make(map[string]bool)
+	return m
+}
+
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return nil
+	return m.loadPreview()
+}
+
+// loadPreview returns a command that renders the preview of the
+// highlighted item, unless it is hidden or already loaded
+func (m Model) loadPreview() tea.Cmd {
+	if !m.showPreview || m.preview == nil || m.cursor >= len(m.filtered) {
+		return nil
+	}
+
+	item := m.filtered[m.cursor]
+	key := item.key()
+	if _, ok := m.previews[key]; ok || m.loading[key] {
+		return nil
+	}
+
+	// Mark as loading so that moving back and forth doesn't queue it again
+	m.loading[key] = true
+	preview, ctx := m.preview, m.previewCtx
+	return func() tea.Msg {
+		return previewMsg{key: key, content: preview(ctx, item)}
+	}
 }
 
 // Update handles messages
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.confirming {
+		return m.updateConfirm(msg)
+	}
+
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case previewMsg:
+		m.previews[msg.key] = msg.content
+		delete(m.loading, msg.key)
+		return m, nil
+
 	case tea.KeyMsg:
 		switch msg.String() {
+		case "tab":
+			if m.preview != nil {
+				m.showPreview = !m.showPreview
+				return m, m.loadPreview()
+			}
+
 		case "ctrl+c", "esc":
-			if m.filtering {
+			if m.filtering || (m.multi && m.textInput.Value() != "") {
 				m.filtering = false
 				m.textInput.Reset()
 				m.filtered = m.items
+				m.matches = nil
 				m.cursor = 0
-				return m, nil
+				return m, m.loadPreview()
 			}
 			m.quitting = true
 			return m, tea.Quit
@@ -104,23 +221,63 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter":
+			if m.multi {
+				if m.filtering {
+					// Keep the filter and return to the list
+					m.filtering = false
+					m.textInput.Blur()
+					return m, nil
+				}
+				if len(m.checkedItems()) == 0 && m.cursor < len(m.filtered) {
+					m.checked[m.filtered[m.cursor].key()] = true
+				}
+				if len(m.checkedItems()) > 0 {
+					m.confirming = true
+				}
+				return m, nil
+			}
 			if len(m.filtered) > 0 && m.cursor < len(m.filtered) {
 				m.selected = &m.filtered[m.cursor]
 			}
 			return m, tea.Quit
 
+		case " ":
+			if m.multi && !m.filtering && m.cursor < len(m.filtered) {
+				k := m.filtered[m.cursor].key()
+				m.checked[k] = !m.checked[k]
+				return m, nil
+			}
+
+		case "a":
+			if m.multi && !m.filtering {
+				// Select all filtered items, or clear them if all are selected
+				all := true
+				for _, item := range m.filtered {
+					if !m.checked[item.key()] {
+						all = false
+						break
+					}
+				}
+				for _, item := range m.filtered {
+					m.checked[item.key()] = !all
+				}
+				return m, nil
+			}
+
 		case "up", "k":
-			if !m.filtering {
+			if !m.filtering || msg.String() == "up" {
 				if m.cursor > 0 {
 					m.cursor--
 				}
+				return m, m.loadPreview()
 			}
 
 		case "down", "j":
-			if !m.filtering {
+			if !m.filtering || msg.String() == "down" {
 				if m.cursor < len(m.filtered)-1 {
 					m.cursor++
 				}
+				return m, m.loadPreview()
 			}
 
 		case "/":
@@ -134,23 +291,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Handle text input for filtering
 	if m.filtering {
+		previous := m.textInput.Value()
 		var cmd tea.Cmd
 		m.textInput, cmd = m.textInput.Update(msg)
 
-		// Apply filter
-		query := strings.ToLower(m.textInput.Value())
+		// Apply filter, best matches first
+		query := m.textInput.Value()
 		if query == "" {
 			m.filtered = m.items
+			m.matches = nil
 		} else {
-			m.filtered = fuzzyFilter(m.items, query)
+			m.filtered, m.matches = fuzzyFilter(m.items, query)
 		}
 
-		// Reset cursor if out of bounds
-		if m.cursor >= len(m.filtered) {
-			m.cursor = max(0, len(m.filtered)-1)
+		// Move to the best match when the query changes
+		if query != previous {
+			m.cursor = 0
 		}
 
-		return m, cmd
+		return m, tea.Batch(cmd, m.loadPreview())
 	}
 
 	return m, nil
@@ -162,6 +321,10 @@ func (m Model) View() string {
 		return ""
 	}
 
+	if m.confirming {
+		return m.viewConfirm()
+	}
+
 	var b strings.Builder
 
 	// Title
@@ -173,6 +336,9 @@ func (m Model) View() string {
 		b.WriteString(filterStyle.Render("Filter: "))
 		b.WriteString(m.textInput.View())
 		b.WriteString("\n\n")
+	} else if m.multi && m.textInput.Value() != "" {
+		b.WriteString(filterStyle.Render("Filter: " + m.textInput.Value()))
+		b.WriteString("\n\n")
 	}
 
 	// Items
@@ -185,16 +351,34 @@ func (m Model) View() string {
 			style = selectedStyle
 		}
 
-		line := cursor + style.Render(item.Name)
+		if m.multi {
+			if m.checked[item.key()] {
+				cursor += checkedStyle.Render("[x] ")
+			} else {
+				cursor += "[ ] "
+			}
+		}
+
+		match := m.matches[item.key()]
+		line := cursor + highlight(item.Name, match.namePos, style)
 
 		if item.Description != "" {
-			line += descStyle.Render(" (" + item.Description + ")")
+			// Shift the positions past the opening " ("
+			descPos := make([]int, len(match.descPos))
+			for i, pos := range match.descPos {
+				descPos[i] = pos + 2
+			}
+			line += highlight(" ("+item.Description+")", descPos, descStyle)
 		}
 
 		if item.IsCurrent {
 			line += currentStyle.Render(" *")
 		}
 
+		if m.multi && item.IsDirty {
+			line += dirtyStyle.Render(" [modified]")
+		}
+
 		b.WriteString(line)
 		b.WriteString("\n")
 	}
@@ -206,44 +390,165 @@ func (m Model) View() string {
 
 	// Help
 	help := "↑/k up • ↓/j down • / filter • enter select • esc/q quit"
+	if m.multi {
+		help = fmt.Sprintf("%d selected • space toggle • a all • / filter • enter confirm • esc/q quit", len(m.checkedItems()))
+	}
+	if m.preview != nil {
+		help = strings.Replace(help, "enter", "tab preview • enter", 1)
+	}
 	b.WriteString(helpStyle.Render(help))
 
-	return b.String()
+	if !m.showPreview || m.preview == nil || len(m.filtered) == 0 {
+		return b.String()
+	}
+
+	// Show the preview beside the list on wide terminals, below otherwise
+	list := b.String()
+	if m.width >= minSplitWidth {
+		pane := m.viewPreview(m.width - lipgloss.Width(list) - 6)
+		return lipgloss.JoinHorizontal(lipgloss.Top, list, "  ", pane)
+	}
+	width := m.width - 4
+	if width <= 0 {
+		width = 76
+	}
+	return list + "\n" + m.viewPreview(width)
 }
 
-// Selected returns the selected item
-func (m Model) Selected() *Item {
-	return m.selected
+// viewPreview renders the preview of the highlighted item
+func (m Model) viewPreview(width int) string {
+	content, ok := m.previews[m.filtered[m.cursor].key()]
+	switch {
+	case !ok:
+		content = descStyle.Render("Loading...")
+	case strings.TrimSpace(content) == "":
+		content = descStyle.Render("(no preview)")
+	}
+
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if len(lines) > maxPreviewLines {
+		lines = append(lines[:maxPreviewLines-1], fmt.Sprintf("… %d more lines", len(lines)-maxPreviewLines+1))
+	}
+	// Leave room for the horizontal padding
+	textWidth := width - 2
+	for i, line := range lines {
+		if textWidth > 0 && lipgloss.Width(line) > textWidth {
+			lines[i] = truncate(line, textWidth)
+		}
+	}
+
+	return previewStyle.Width(width).Render(strings.Join(lines, "\n"))
+}
+
+// truncate shortens s to at most width cells
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes)) > width-1 {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// updateConfirm handles messages on the confirmation screen
+func (m Model) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch key.String() {
+	case "y", "Y", "enter":
+		m.confirmed = m.checkedItems()
+		return m, tea.Quit
+	case "n", "N", "esc":
+		m.confirming = false
+	case "ctrl+c", "q":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
 }
 
-// fuzzyFilter filters items by query
-func fuzzyFilter(items []Item, query string) []Item {
+// viewConfirm renders the confirmation screen
+func (m Model) viewConfirm() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(m.confirmPrompt))
+	b.WriteString("\n")
+
+	for _, item := range m.checkedItems() {
+		line := "  " + selectedStyle.Render(item.Name)
+		if item.Path != "" {
+			line += descStyle.Render(item.Path)
+		}
+		if item.IsDirty {
+			line += dirtyStyle.Render(" [uncommitted changes]")
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(helpStyle.Render("y/enter confirm • n/esc back • q quit"))
+
+	return b.String()
+}
+
+// checkedItems returns the checked items in their original order
+func (m Model) checkedItems() []Item {
 	var result []Item
-	for _, item := range items {
-		name := strings.ToLower(item.Name)
-		if strings.Contains(name, query) || fuzzyMatch(name, query) {
+	for _, item := range m.items {
+		if m.checked[item.key()] {
 			result = append(result, item)
 		}
 	}
 	return result
 }
 
-// fuzzyMatch performs fuzzy matching
-func fuzzyMatch(text, pattern string) bool {
-	patternIdx := 0
-	for i := 0; i < len(text) && patternIdx < len(pattern); i++ {
-		if text[i] == pattern[patternIdx] {
-			patternIdx++
-		}
-	}
-	return patternIdx == len(pattern)
+// Confirmed returns the items confirmed in multi-select mode
+func (m Model) Confirmed() []Item {
+	return m.confirmed
+}
+
+// Selected returns the selected item
+func (m Model) Selected() *Item {
+	return m.selected
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+// highlight renders text with style, emphasizing the runes at positions
+func highlight(text string, positions []int, style lipgloss.Style) string {
+	if len(positions) == 0 {
+		return style.Render(text)
+	}
+
+	// Render runs of plain and matched runes separately, padding only once
+	padding := strings.Repeat(" ", style.GetPaddingLeft())
+	plain := style.UnsetPaddingLeft()
+	matched := matchStyle.Inherit(plain)
+
+	isMatch := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		isMatch[pos] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(padding)
+
+	runes := []rune(text)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && isMatch[i] == isMatch[start] {
+			continue
+		}
+		run := string(runes[start:i])
+		if isMatch[start] {
+			b.WriteString(matched.Render(run))
+		} else {
+			b.WriteString(plain.Render(run))
+		}
+		start = i
 	}
-	return b
+
+	return b.String()
 }
 
 // SelectBranch opens a TUI to select a branch
@@ -261,8 +566,20 @@ func SelectBranch(items []Item) (*Item, error) {
 
 // SelectWorktree opens a TUI to select a worktree
 func SelectWorktree(items []Item) (*Item, error) {
-	m := NewModel("Select a worktree:", items)
-	p := tea.NewProgram(m, tea.WithOutput(nil))
+	return SelectWorktreeWithPreview(context.Background(), items, nil)
+}
+
+// SelectWorktreeWithPreview opens a TUI to select a worktree with a preview
+// pane rendered by preview. The pane is toggled with tab. Previews still
+// loading are cancelled when the selector closes, and the selector closes
+// when ctx ends.
+func SelectWorktreeWithPreview(ctx context.Context, items []Item, preview PreviewFunc) (*Item, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m := NewPreviewModel("Select a worktree:", items, preview)
+	m.previewCtx = ctx
+	p := tea.NewProgram(m, tea.WithOutput(nil), tea.WithContext(ctx))
 
 	finalModel, err := p.Run()
 	if err != nil {
@@ -271,3 +588,17 @@ func SelectWorktree(items []Item) (*Item, error) {
 
 	return finalModel.(Model).Selected(), nil
 }
+
+// SelectWorktrees opens a TUI to select several worktrees. The selection
+// is shown with prompt for confirmation; nil is returned if cancelled.
+func SelectWorktrees(items []Item, prompt string) ([]Item, error) {
+	m := NewMultiModel("Select worktrees:", items, prompt)
+	p := tea.NewProgram(m, tea.WithOutput(nil))
+
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, fmt.Errorf("TUI error: %w", err)
+	}
+
+	return finalModel.(Model).Confirmed(), nil
+}
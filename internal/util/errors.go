@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ErrorCode represents different error types
@@ -16,6 +17,10 @@ const (
 	ErrConfigInvalid
 	ErrPermissionDenied
 	ErrGitCommand
+	ErrBranchAmbiguous
+	ErrWorktreeLocked
+	ErrResourceExhausted
+	ErrGitTimeout
 )
 
 // WTError is a custom error type with error codes
@@ -52,6 +57,14 @@ func BranchNotFoundError(branch string) *WTError {
 	}
 }
 
+func BranchAmbiguousError(branch string, candidates []string) *WTError {
+	return &WTError{
+		Code: ErrBranchAmbiguous,
+		Message: fmt.Sprintf("branch '%s' exists on several remotes (%s), use --remote to choose",
+			branch, strings.Join(candidates, ", ")),
+	}
+}
+
 func WorktreeExistsError(path string) *WTError {
 	return &WTError{
 		Code:    ErrWorktreeExists,
@@ -66,6 +79,31 @@ func WorktreeNotFoundError(path string) *WTError {
 	}
 }
 
+func WorktreeLockedError(path, reason string) *WTError {
+	msg := fmt.Sprintf("worktree at '%s' is locked", path)
+	if reason != "" {
+		msg += fmt.Sprintf(" (%s)", reason)
+	}
+	return &WTError{
+		Code:    ErrWorktreeLocked,
+		Message: msg + ", use -f -f to remove it anyway",
+	}
+}
+
+func PortsExhaustedError(name string, min, max int) *WTError {
+	return &WTError{
+		Code:    ErrResourceExhausted,
+		Message: fmt.Sprintf("no free port left for '%s' in range %d-%d", name, min, max),
+	}
+}
+
+func ConfigInvalidError(msg string) *WTError {
+	return &WTError{
+		Code:    ErrConfigInvalid,
+		Message: fmt.Sprintf("invalid configuration: %s", msg),
+	}
+}
+
 func GitCommandError(cmd string, err error) *WTError {
 	return &WTError{
 		Code:    ErrGitCommand,
@@ -85,3 +123,10 @@ func GitCommandErrorWithOutput(cmd string, err error, output string) *WTError {
 	}
 	return GitCommandError(cmd, err)
 }
+
+func GitTimeoutError(cmd string, timeout time.Duration) *WTError {
+	return &WTError{
+		Code:    ErrGitTimeout,
+		Message: fmt.Sprintf("git command timed out after %s: %s", timeout, cmd),
+	}
+}
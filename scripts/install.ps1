@@ -196,28 +196,9 @@ function Setup-PowerShellIntegration {
 
 # wt - Git Worktree Manager shell integration
 # wt-shell-integration
-function Invoke-Wt {
-    param([Parameter(ValueFromRemainingArguments)]$Args)
-
-    if ($Args.Count -gt 0 -and ($Args[0] -eq "add" -or $Args[0] -eq "select")) {
-        $allArgs = $Args + @("--print-path")
-        $output = & wt.exe @allArgs 2>&1
-        $exitCode = $LASTEXITCODE
-
-        if ($exitCode -eq 0 -and $output -and (Test-Path $output -PathType Container)) {
-            Set-Location $output
-            Write-Host "Switched to: $output" -ForegroundColor Green
-        }
-        else {
-            Write-Output $output
-        }
-    }
-    else {
-        & wt.exe @Args
-    }
+if (Get-Command wt.exe -ErrorAction SilentlyContinue) {
+    Invoke-Expression (& wt.exe shell-init powershell | Out-String)
 }
-
-Set-Alias -Name wt -Value Invoke-Wt -Scope Global -Force
 '@
 
     Add-Content -Path $profilePath -Value $integration
@@ -69,11 +69,12 @@ get_latest_version() {
 # Setup shell integration
 setup_shell_integration() {
     local shell_rc=""
+    local shell_name=""
 
     # Detect shell
     case "$SHELL" in
-        */bash) shell_rc="$HOME/.bashrc";;
-        */zsh)  shell_rc="$HOME/.zshrc";;
+        */bash) shell_rc="$HOME/.bashrc"; shell_name="bash";;
+        */zsh)  shell_rc="$HOME/.zshrc"; shell_name="zsh";;
         *)      return;;
     esac
 
@@ -105,24 +106,12 @@ setup_shell_integration() {
     fi
 
     # Add new integration with markers
-    cat >> "$shell_rc" << 'EOF'
+    cat >> "$shell_rc" << EOF
 
 # wt - Git Worktree Manager shell integration
-wt() {
-    if [ "$1" = "add" ] || [ "$1" = "select" ]; then
-        local output
-        output=$(command wt "$@" --print-path 2>&1)
-        local exit_code=$?
-        if [ $exit_code -eq 0 ] && [ -n "$output" ] && [ -d "$output" ]; then
-            cd "$output" && echo "Switched to: $output"
-        else
-            echo "$output"
-            return $exit_code
-        fi
-    else
-        command wt "$@"
-    fi
-}
+if command -v wt >/dev/null 2>&1; then
+    eval "\$(command wt shell-init ${shell_name})"
+fi
 # wt - end
 EOF
 